@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/christophberger/publer-analytics-report/report"
+)
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dbPath := fs.String("db", "", "path to the SQLite database (default $PUBLER_DB or analytics.db)")
+	configFile := fs.String("config", report.DefaultConfigFile, "path to the configuration file with the database settings (default searches ./config.yaml, then the user config directory)")
+	out := fs.String("out", "", "JSON file to write (default stdout)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s export [flags]\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	return report.ExportDB(context.Background(), *dbPath, *configFile, *out)
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dbPath := fs.String("db", "", "path of the new SQLite database (default $PUBLER_DB or analytics.db)")
+	configFile := fs.String("config", report.DefaultConfigFile, "path to the configuration file with the database settings (default searches ./config.yaml, then the user config directory)")
+	in := fs.String("in", "", "JSON file written by export")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s import --in <file> [flags]\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *in == "" {
+		fs.Usage()
+		return usageError{errors.New("missing --in file")}
+	}
+	return report.ImportDB(context.Background(), *dbPath, *configFile, *in)
+}
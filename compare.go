@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/christophberger/publer-analytics-report/report"
+)
+
+func runCompare(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	from := fs.String("from", "", "earlier period to compare, as YYYY-MM")
+	to := fs.String("to", "", "later period to compare, as YYYY-MM")
+	workspace := fs.String("workspace", "", "workspace to compare (default the only one in the database)")
+	dbPath := fs.String("db", "", "path to the SQLite database (default $PUBLER_DB or analytics.db)")
+	configFile := fs.String("config", report.DefaultConfigFile, "path to the configuration file with the database settings (default searches ./config.yaml, then the user config directory)")
+	var output string
+	fs.StringVar(&output, "o", "", "output file or directory for the comparison (shorthand; default stdout)")
+	fs.StringVar(&output, "output", "", "output file or directory for the comparison (default stdout)")
+	format := fs.String("format", "md", "comparison format: md or json")
+	top := fs.Int("top", 5, "number of rising and falling hashtags to show (0 or less shows all)")
+	decimals := fs.Int("decimals", report.DefaultDecimals, "number of decimal places of percentages in the comparison")
+	rateBasis := fs.String("engagement-rate-basis", "", "recompute the engagement rate as engagements per reach or per followers (default Publer's rate, which is per reach)")
+	verbose := fs.Bool("verbose", false, "log the workspace and periods to stderr")
+	quiet := fs.Bool("quiet", false, "print nothing but errors and the comparison on stdout")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s compare --from YYYY-MM --to YYYY-MM [flags]\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	setupLogging(*verbose, *quiet)
+
+	if err := report.ValidateCompare(*from, *to, *format); err != nil {
+		return usageError{err}
+	}
+	basis, err := report.ParseRateBasis(*rateBasis)
+	if err != nil {
+		return usageError{fmt.Errorf("error parsing engagement rate basis: %w", err)}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return report.RunCompare(ctx, report.CompareOptions{
+		From:       *from,
+		To:         *to,
+		Workspace:  *workspace,
+		DBPath:     *dbPath,
+		ConfigFile: *configFile,
+		Output:     output,
+		Format:     *format,
+		Report:     report.ReportOptions{Top: *top, RateBasis: basis, Decimals: *decimals},
+		Quiet:      *quiet,
+	})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/christophberger/publer-analytics-report/report"
+)
+
+func runIngest(args []string) error {
+	fs := flag.NewFlagSet("ingest", flag.ExitOnError)
+	dbPath := fs.String("db", "", "path to the SQLite database (default $PUBLER_DB or analytics.db)")
+	configFile := fs.String("config", report.DefaultConfigFile, "path to the configuration file (default searches ./config.yaml, then the user config directory)")
+	workspace := fs.String("workspace", "", "workspace name to use instead of the one in the overview file")
+	recursive := fs.Bool("recursive", false, "treat the directory as a parent folder and ingest every subdirectory with CSV files")
+	latest := fs.Bool("latest", false, "pick the most recent file when a directory holds several files of one type")
+	force := fs.Bool("force", false, "process CSV files even if they cover different periods")
+	strict := fs.Bool("strict", false, "fail if a numeric cell of the CSV files does not hold a number, instead of reading it as 0 with a warning")
+	strictCSV := fs.Bool("strict-csv", false, "fail if a data row of the CSV files does not have as many fields as the header row, instead of skipping unreadable rows with a warning")
+	redactDB := fs.Bool("redact-db", false, "store the posts without their text in the database")
+	delimiter := fs.String("delimiter", "", `CSV field separator: ",", ";", or "tab" (default detects it per file)`)
+	verbose := fs.Bool("verbose", false, "log discovered files, row counts, and periods to stderr")
+	quiet := fs.Bool("quiet", false, "print nothing but errors")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s ingest [flags] <file-or-directory>\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return usageError{errors.New("missing file or directory argument")}
+	}
+	ws := strings.TrimSpace(*workspace)
+	if ws != "" && *recursive {
+		return usageError{errors.New("--workspace cannot be combined with --recursive")}
+	}
+
+	setupLogging(*verbose, *quiet)
+
+	delim, err := report.ParseDelimiter(*delimiter)
+	if err != nil {
+		return usageError{fmt.Errorf("error parsing delimiter: %w", err)}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return report.RunContext(ctx, report.Options{
+		Input:      fs.Arg(0),
+		Recursive:  *recursive,
+		IngestOnly: true,
+		Workspace:  ws,
+		ConfigFile: *configFile,
+		DBPath:     *dbPath,
+		Delimiter:  delim,
+		Latest:     *latest,
+		Force:      *force,
+		Quiet:      *quiet,
+		Strict:     *strict,
+		StrictCSV:  *strictCSV,
+		RedactDB:   *redactDB,
+	})
+}
@@ -0,0 +1,386 @@
+// Package insights asks an OpenAI-compatible chat completions endpoint or the
+// Anthropic Messages API for report insights and next steps. The API calls go
+// through the Generator interface, so they can be replaced offline.
+package insights
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/christophberger/publer-analytics-report/model"
+)
+
+// Generator returns the model's response to a prompt. Client implements it
+// for the configured API; tests can pass a fake that returns canned text.
+type Generator interface {
+	Generate(ctx context.Context, prompt string) (string, error)
+}
+
+func GenerateInsights(ctx context.Context, data *model.ReportData, gen Generator, prompts *Prompts) (string, error) {
+	prompt, err := render(prompts.insights, data)
+	if err != nil {
+		return "", err
+	}
+
+	return gen.Generate(ctx, prompt)
+}
+
+func GenerateNextSteps(ctx context.Context, data *model.ReportData, gen Generator, prompts *Prompts) (string, error) {
+	prompt, err := render(prompts.nextSteps, data)
+	if err != nil {
+		return "", err
+	}
+
+	return gen.Generate(ctx, prompt)
+}
+
+// Client sends prompts to the API described by Config.
+type Client struct {
+	Config *model.Config
+	// HTTPClient is used for the requests. Nil uses a client with the
+	// timeout of Config.API.Timeout, or 30 seconds. With Stream, the timeout
+	// only limits the wait for the response headers.
+	HTTPClient *http.Client
+	// Stream requests the completion as server-sent events from the
+	// OpenAI-compatible API. The Anthropic API is always called without.
+	Stream bool
+	// Progress, if set, receives the streamed text as it arrives.
+	Progress io.Writer
+}
+
+func NewClient(config *model.Config) *Client {
+	return &Client{Config: config}
+}
+
+func (c *Client) Generate(ctx context.Context, prompt string) (string, error) {
+	config := c.Config
+	var apiKey string
+	if config.API.APIKeyEnv != "" {
+		apiKey = os.Getenv(config.API.APIKeyEnv)
+	}
+	if apiKey == "" && config.AuthRequired() {
+		return "", fmt.Errorf("API key environment variable %s not set", config.API.APIKeyEnv)
+	}
+
+	type Message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+
+	type Request struct {
+		Model       string    `json:"model"`
+		Messages    []Message `json:"messages"`
+		MaxTokens   int       `json:"max_tokens"`
+		Temperature float64   `json:"temperature"`
+		Stream      bool      `json:"stream,omitempty"`
+	}
+
+	request := Request{
+		Model: config.API.Model,
+		Messages: []Message{
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		},
+		MaxTokens:   defaultMaxTokens,
+		Temperature: defaultTemperature,
+	}
+	if config.API.MaxTokens > 0 {
+		request.MaxTokens = config.API.MaxTokens
+	}
+	if config.API.Temperature != nil {
+		request.Temperature = *config.API.Temperature
+	}
+
+	path := "/chat/completions"
+	headers := map[string]string{"Authorization": "Bearer " + apiKey}
+	decode := decodeOpenAI
+	switch strings.ToLower(config.API.Provider) {
+	case "", "openai":
+		if c.Stream {
+			request.Stream = true
+			decode = func(r io.Reader) (string, error) { return decodeOpenAIStream(r, c.Progress) }
+		}
+	case "anthropic":
+		path = "/messages"
+		headers = map[string]string{"x-api-key": apiKey, "anthropic-version": anthropicVersion}
+		decode = decodeAnthropic
+	default:
+		return "", fmt.Errorf("unsupported API provider %q, expected openai or anthropic", config.API.Provider)
+	}
+
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("error marshaling request: %v", err)
+	}
+	if apiKey == "" {
+		// Servers without authentication get no credential headers.
+		delete(headers, "Authorization")
+		delete(headers, "x-api-key")
+	}
+
+	attempts := config.API.MaxAttempts
+	if attempts < 1 {
+		attempts = defaultMaxAttempts
+	}
+	delay := config.API.RetryDelay
+	if delay <= 0 {
+		delay = defaultRetryDelay
+	}
+
+	timeout := cmp.Or(config.API.Timeout, defaultTimeout)
+	client := c.HTTPClient
+	switch {
+	case client != nil:
+		timeout = client.Timeout
+	case request.Stream:
+		// A streamed completion may well take longer than the timeout, so
+		// it only limits the wait for the response to begin.
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.ResponseHeaderTimeout = timeout
+		client = &http.Client{Transport: transport}
+	default:
+		client = &http.Client{Timeout: timeout}
+	}
+	var resp *http.Response
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "POST", config.API.BaseURL+path, bytes.NewReader(requestBody))
+		if err != nil {
+			return "", err
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+
+		start := time.Now()
+		resp, err = client.Do(req)
+		if err != nil {
+			slog.Debug("API request failed", "attempt", attempt, "duration", time.Since(start), "err", err)
+		} else {
+			slog.Debug("API request", "attempt", attempt, "status", resp.StatusCode, "duration", time.Since(start))
+		}
+		if attempt >= attempts || !shouldRetry(resp, err) {
+			if err != nil {
+				return "", err
+			}
+			break
+		}
+
+		wait := retryAfter(resp, delay<<(attempt-1))
+		// No wait is longer than a request may take, so that a completion
+		// takes at most 2*attempts-1 times the timeout.
+		if timeout > 0 {
+			wait = min(wait, timeout)
+		}
+		slog.Info("retrying API request", "attempt", attempt+1, "wait", wait)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", apiError(resp)
+	}
+
+	// A backend that does not support streaming answers with a plain
+	// completion instead.
+	if request.Stream && !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		slog.Debug("API response is not streamed", "content-type", resp.Header.Get("Content-Type"))
+		decode = decodeOpenAI
+	}
+
+	content, err := decode(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(content), nil
+}
+
+func decodeOpenAI(r io.Reader) (string, error) {
+	var response struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&response); err != nil {
+		return "", fmt.Errorf("error decoding response: %v", err)
+	}
+
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("no choices in response")
+	}
+
+	return response.Choices[0].Message.Content, nil
+}
+
+// decodeOpenAIStream concatenates the content deltas of a streamed
+// completion and copies them to progress, if set. Events that cannot be
+// parsed are skipped.
+func decodeOpenAIStream(r io.Reader, progress io.Writer) (string, error) {
+	var sb strings.Builder
+	skipped := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			skipped++
+			continue
+		}
+		for _, choice := range chunk.Choices {
+			sb.WriteString(choice.Delta.Content)
+			if progress != nil {
+				io.WriteString(progress, choice.Delta.Content)
+			}
+		}
+	}
+	if progress != nil && sb.Len() > 0 {
+		io.WriteString(progress, "\n")
+	}
+	if skipped > 0 {
+		slog.Warn("skipped unreadable events in the streamed API response", "events", skipped)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("error reading streamed response: %v", err)
+	}
+	if sb.Len() == 0 {
+		return "", fmt.Errorf("no content in streamed response")
+	}
+
+	return sb.String(), nil
+}
+
+func decodeAnthropic(r io.Reader) (string, error) {
+	var response struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&response); err != nil {
+		return "", fmt.Errorf("error decoding response: %v", err)
+	}
+
+	var sb strings.Builder
+	for _, c := range response.Content {
+		if c.Type == "text" {
+			sb.WriteString(c.Text)
+		}
+	}
+	if sb.Len() == 0 {
+		return "", fmt.Errorf("no text content in response")
+	}
+
+	return sb.String(), nil
+}
+
+const (
+	anthropicVersion   = "2023-06-01"
+	defaultMaxTokens   = 500
+	defaultTemperature = 0.7
+	defaultMaxAttempts = 3
+	defaultRetryDelay  = time.Second
+	defaultTimeout     = 30 * time.Second
+	maxErrorBodyLen    = 500
+)
+
+func apiError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	var payload struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	msg := ""
+	if json.Unmarshal(body, &payload) == nil {
+		msg = payload.Error.Message
+	}
+	if msg == "" {
+		msg = strings.TrimSpace(string(body))
+	}
+	if len(msg) > maxErrorBodyLen {
+		// Cut before the rune that the limit falls into.
+		n := maxErrorBodyLen
+		for n > 0 && !utf8.RuneStart(msg[n]) {
+			n--
+		}
+		msg = msg[:n] + "..."
+	}
+
+	if msg == "" {
+		return fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+	}
+	return fmt.Errorf("API request failed with status: %d: %s", resp.StatusCode, msg)
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		return errors.As(err, &netErr) && netErr.Timeout()
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+func retryAfter(resp *http.Response, fallback time.Duration) time.Duration {
+	if resp == nil {
+		return fallback
+	}
+	h := resp.Header.Get("Retry-After")
+	if h == "" {
+		return fallback
+	}
+	if secs, err := strconv.Atoi(h); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return fallback
+}
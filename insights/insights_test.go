@@ -0,0 +1,175 @@
+package insights
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/christophberger/publer-analytics-report/model"
+)
+
+// testConfig returns a config for the server at url that needs no API key.
+func testConfig(provider, url string) *model.Config {
+	noAuth := false
+	c := &model.Config{}
+	c.API.Provider = provider
+	c.API.BaseURL = url
+	c.API.Model = "test-model"
+	c.API.AuthRequired = &noAuth
+	c.API.MaxAttempts = 1
+	return c
+}
+
+func TestClientGenerate(t *testing.T) {
+	tests := []struct {
+		provider string
+		path     string
+		response string
+		want     string
+	}{
+		{"openai", "/chat/completions", `{"choices":[{"message":{"content":"  insights\n"}}]}`, "insights"},
+		{"anthropic", "/messages", `{"content":[{"type":"text","text":"next "},{"type":"tool_use"},{"type":"text","text":"steps"}]}`, "next steps"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			var request struct {
+				Model     string `json:"model"`
+				MaxTokens int    `json:"max_tokens"`
+				Messages  []struct {
+					Role    string `json:"role"`
+					Content string `json:"content"`
+				} `json:"messages"`
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.path {
+					t.Errorf("request to %s, want %s", r.URL.Path, tt.path)
+				}
+				if r.Header.Get("Authorization") != "" || r.Header.Get("x-api-key") != "" {
+					t.Error("credentials sent without a key")
+				}
+				if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+					t.Error(err)
+				}
+				io.WriteString(w, tt.response)
+			}))
+			defer srv.Close()
+
+			got, err := NewClient(testConfig(tt.provider, srv.URL)).Generate(context.Background(), "the prompt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Generate = %q, want %q", got, tt.want)
+			}
+			if request.Model != "test-model" || request.MaxTokens != defaultMaxTokens {
+				t.Errorf("request model %q with %d tokens", request.Model, request.MaxTokens)
+			}
+			if len(request.Messages) != 1 || request.Messages[0].Content != "the prompt" {
+				t.Errorf("request messages = %+v", request.Messages)
+			}
+		})
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		status   int
+		body     string
+		want     string
+	}{
+		{"api error", "openai", http.StatusBadRequest, `{"error":{"message":"bad model"}}`, "status: 400: bad model"},
+		{"plain error", "openai", http.StatusUnauthorized, "denied", "status: 401: denied"},
+		// Byte 500 is the second byte of an é.
+		{"long error", "openai", http.StatusInternalServerError, "a" + strings.Repeat("é", 300), "status: 500: a" + strings.Repeat("é", 249) + "..."},
+		{"no choices", "openai", http.StatusOK, `{"choices":[]}`, "no choices"},
+		{"no text", "anthropic", http.StatusOK, `{"content":[]}`, "no text content"},
+		{"unknown provider", "gemini", http.StatusOK, "", "unsupported API provider"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+
+			_, err := NewClient(testConfig(tt.provider, srv.URL)).Generate(context.Background(), "prompt")
+			if err == nil || !strings.Contains(err.Error(), tt.want) || !utf8.ValidString(err.Error()) {
+				t.Errorf("Generate error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientMissingKey(t *testing.T) {
+	c := testConfig("openai", "http://127.0.0.1:1")
+	c.API.AuthRequired = nil
+	c.API.APIKeyEnv = "PUBLER_TEST_UNSET_KEY"
+	t.Setenv("PUBLER_TEST_UNSET_KEY", "")
+	if _, err := NewClient(c).Generate(context.Background(), "prompt"); err == nil || !strings.Contains(err.Error(), "PUBLER_TEST_UNSET_KEY") {
+		t.Errorf("Generate error = %v, want one naming the key variable", err)
+	}
+}
+
+func TestDefaultPrompts(t *testing.T) {
+	data := &model.ReportData{Month: "July 2025", Period: "2025-07", Followers: 4750, HasPrevious: true, FollowersChange: 43}
+	var sb strings.Builder
+	if err := DumpPrompts(&sb, data, DefaultPrompts()); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"=== insights prompt", "=== next steps prompt", "July 2025 (2025-07)", "Followers: 4750 (+43 vs. previous month)"} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("prompts lack %q:\n%s", want, sb.String())
+		}
+	}
+}
+
+func TestClientStreamOutlastsTimeout(t *testing.T) {
+	const timeout = 200 * time.Millisecond
+	tests := []struct {
+		name       string
+		headerWait time.Duration
+		want       string
+		err        bool
+	}{
+		// The events arrive over twice the timeout, but the stream begins
+		// at once.
+		{"slow stream", 0, "one two three", false},
+		{"slow response", 2 * timeout, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(tt.headerWait)
+				w.Header().Set("Content-Type", "text/event-stream")
+				for i, word := range []string{"one", " two", " three"} {
+					if i > 0 {
+						time.Sleep(timeout)
+					}
+					fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", word)
+					w.(http.Flusher).Flush()
+				}
+				io.WriteString(w, "data: [DONE]\n\n")
+			}))
+			defer srv.Close()
+
+			config := testConfig("openai", srv.URL)
+			config.API.Timeout = timeout
+			client := NewClient(config)
+			client.Stream = true
+			got, err := client.Generate(context.Background(), "the prompt")
+			if (err != nil) != tt.err || got != tt.want {
+				t.Errorf("Generate = %q, %v, want %q, error %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package insights
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/christophberger/publer-analytics-report/model"
+)
+
+const defaultInsightsPrompt = `Based on the following social media analytics data for {{.Month}} ({{.Period}}):
+
+- Followers: {{.Followers}}{{if .HasPrevious}} ({{printf "%+d" .FollowersChange}} vs. previous month){{end}}
+- Reach: {{.Reach}}{{if .HasPrevious}} ({{printf "%+.1f" .ReachChange}}% vs. previous month){{end}}
+- Engagements: {{.Engagements}}{{if .HasPrevious}} ({{printf "%+.1f" .EngagementsChange}}% vs. previous month){{end}}
+- Engagement Rate{{with .RateBasis}} (engagements / {{.}}){{end}}: {{printf "%.2f" .EngagementRate}}%{{if .HasPrevious}} ({{printf "%+.1f" .EngagementRateChange}}% vs. previous month){{end}}
+{{if .TopPosts}}
+Top performing posts by {{.RankBy}}:
+{{range .TopPosts}}- "{{truncate .PostText 150}}" ({{.Reactions}} reactions, {{.Comments}} comments, {{.Shares}} shares, reach {{.Reach}})
+{{end}}{{end}}{{if .TopHashtags}}
+Top hashtags by score:
+{{range .TopHashtags}}- {{.Hashtag}} (score {{.Score}}, reach {{.Reach}})
+{{end}}{{end}}
+Please provide insights and recommendations for improving social media performance. Focus on what's working well and what could be improved, and comment on the month-over-month trends where available.`
+
+const defaultNextStepsPrompt = `Based on the social media analytics data for {{.Month}} ({{.Period}}):
+
+- Followers: {{.Followers}}
+- Reach: {{.Reach}}  
+- Engagements: {{.Engagements}}
+- Engagement Rate{{with .RateBasis}} (engagements / {{.}}){{end}}: {{printf "%.2f" .EngagementRate}}%
+
+Please suggest specific next steps and action items to optimize KPIs for the next month. Include concrete, actionable recommendations.`
+
+var promptFuncs = template.FuncMap{
+	"truncate": func(s string, length int) string {
+		clean := strings.Join(strings.Fields(s), " ")
+		r := []rune(clean)
+		if len(r) <= length {
+			return clean
+		}
+		return strings.TrimRight(string(r[:length]), " ") + "..."
+	},
+}
+
+type Prompts struct {
+	insights  *template.Template
+	nextSteps *template.Template
+}
+
+func DefaultPrompts() *Prompts {
+	return &Prompts{
+		insights:  template.Must(template.New("insights").Funcs(promptFuncs).Parse(defaultInsightsPrompt)),
+		nextSteps: template.Must(template.New("next_steps").Funcs(promptFuncs).Parse(defaultNextStepsPrompt)),
+	}
+}
+
+func LoadPrompts(config *model.Config, baseDir string) (*Prompts, error) {
+	p := DefaultPrompts()
+
+	var err error
+	if config.Prompts.Insights != "" {
+		if p.insights, err = loadPromptTemplate(config.Prompts.Insights, baseDir); err != nil {
+			return nil, err
+		}
+	}
+	if config.Prompts.NextSteps != "" {
+		if p.nextSteps, err = loadPromptTemplate(config.Prompts.NextSteps, baseDir); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+func loadPromptTemplate(path, baseDir string) (*template.Template, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading prompt template: %w", err)
+	}
+
+	t, err := template.New(filepath.Base(path)).Funcs(promptFuncs).Parse(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing prompt template: %w", err)
+	}
+
+	if err := t.Execute(io.Discard, &model.ReportData{}); err != nil {
+		return nil, fmt.Errorf("error in prompt template: %w", err)
+	}
+
+	return t, nil
+}
+
+// DumpPrompts writes the insights and next steps prompts rendered for data
+// to w, exactly as they would be sent. The prompts only see the report
+// data, never the configuration or the API key.
+func DumpPrompts(w io.Writer, data *model.ReportData, prompts *Prompts) error {
+	for _, p := range []struct {
+		name string
+		t    *template.Template
+	}{{"insights", prompts.insights}, {"next steps", prompts.nextSteps}} {
+		prompt, err := render(p.t, data)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "=== %s prompt for %s, %s ===\n%s\n\n", p.name, data.Workspace, data.Month, prompt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// render executes a prompt template. If data asks for a language other
+// than English, it adds an explicit instruction, so that the default
+// prompts and custom ones that do not use {{.Language}} follow it, too.
+func render(t *template.Template, data *model.ReportData) (string, error) {
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("error rendering prompt: %w", err)
+	}
+	if data.Language != "" && data.Language != "English" {
+		fmt.Fprintf(&sb, "\n\nRespond in %s.", data.Language)
+	}
+	return sb.String(), nil
+}
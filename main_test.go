@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/christophberger/publer-analytics-report/report"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, exitOK},
+		{"AI failed", fmt.Errorf("report for Acme, July 2025 written with placeholder text: %w", report.ErrAIFailed), exitAIFailed},
+		{"AI failed in a recursive run", fmt.Errorf("1 of 2 workspaces: %w", report.ErrAIFailed), exitAIFailed},
+		{"usage", usageError{errors.New("flag provided but not defined: -x")}, exitUsage},
+		{"wrapped usage", fmt.Errorf("Validation failed: %w", usageError{errors.New("no input")}), exitUsage},
+		{"failure", errors.New("error opening database"), exitFailure},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("%s: exitCode(%v) = %d, want %d", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestInvalidFlagValues(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func([]string) error
+		args []string
+	}{
+		{"validate delimiter", runValidate, []string{"--delimiter", "pipe", "exports"}},
+		{"serve delimiter", runServe, []string{"--delimiter", "pipe"}},
+		{"serve ranking", runServe, []string{"--rank-by", "likes"}},
+		{"serve hashtag ranking", runServe, []string{"--hashtag-rank-by", "likes"}},
+		{"report-range ranking", runReportRange, []string{"--rank-by", "likes"}},
+		{"report-range hashtag ranking", runReportRange, []string{"--hashtag-rank-by", "likes"}},
+		{"compare without periods", runCompare, nil},
+		{"compare period", runCompare, []string{"--from", "June", "--to", "2025-07"}},
+		{"compare same period", runCompare, []string{"--from", "2025-07", "--to", "2025-07"}},
+		{"compare format", runCompare, []string{"--from", "2025-06", "--to", "2025-07", "--format", "html"}},
+	}
+	for _, tt := range tests {
+		err := tt.run(tt.args)
+		if got := exitCode(err); got != exitUsage {
+			t.Errorf("%s: exit code %d (%v), want %d", tt.name, got, err, exitUsage)
+		}
+	}
+}
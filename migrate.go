@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/christophberger/publer-analytics-report/report"
+)
+
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dbPath := fs.String("db", "", "path to the SQLite database (default $PUBLER_DB or analytics.db)")
+	configFile := fs.String("config", report.DefaultConfigFile, "path to the configuration file with the database settings (default searches ./config.yaml, then the user config directory)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s migrate [flags]\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	return report.Migrate(context.Background(), *dbPath, *configFile)
+}
@@ -0,0 +1,399 @@
+// Package model holds the data types shared by the parser, store, and insights packages.
+package model
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	API struct {
+		Provider  string `yaml:"provider"`
+		BaseURL   string `yaml:"base_url"`
+		APIKeyEnv string `yaml:"api_key_env"`
+		// AuthRequired set to false allows an empty or unset API key, for
+		// local OpenAI-compatible servers. Nil requires the key.
+		AuthRequired *bool         `yaml:"auth_required"`
+		Model        string        `yaml:"model"`
+		MaxTokens    int           `yaml:"max_tokens"`
+		Temperature  *float64      `yaml:"temperature"`
+		MaxAttempts  int           `yaml:"max_attempts"`
+		RetryDelay   time.Duration `yaml:"retry_delay"`
+		// Timeout bounds each request, including reading the response,
+		// except for a streamed one, where it bounds the wait for the
+		// response to begin. Zero selects the default of 30 seconds.
+		Timeout time.Duration `yaml:"timeout"`
+	} `yaml:"api"`
+	// Database tunes the SQLite connection. Zero values keep the defaults.
+	Database struct {
+		BusyTimeout time.Duration `yaml:"busy_timeout"`
+		JournalMode string        `yaml:"journal_mode"`
+	} `yaml:"database"`
+	Prompts struct {
+		Insights  string `yaml:"insights"`
+		NextSteps string `yaml:"next_steps"`
+	} `yaml:"prompts"`
+	// Language is the language of the AI texts, as a tag such as "de" or a
+	// name such as "German". Empty keeps the language of the prompts.
+	Language string `yaml:"language"`
+	// EngagementRateBasis recomputes the engagement rate per "reach" or
+	// per "followers". Empty keeps Publer's rate, which is per reach.
+	EngagementRateBasis string `yaml:"engagement_rate_basis"`
+	// Top is the number of top posts, hashtags, and countries to show,
+	// unless --top is given. Zero or less shows all; nil keeps the default.
+	Top *int `yaml:"top"`
+	// Countries maps country names or codes to the name they are merged
+	// into in the geographic distribution.
+	Countries map[string]string `yaml:"countries"`
+	// Overview, Posts, and Hashtags override the header labels of the
+	// columns the CSV readers look for, as in "reactions_column: Likes".
+	Overview ColumnMap `yaml:"overview"`
+	Posts    ColumnMap `yaml:"posts"`
+	Hashtags ColumnMap `yaml:"hashtags"`
+}
+
+// ColumnMap maps "<field>_column" keys to CSV header labels.
+type ColumnMap map[string]string
+
+// Fields returns the mapping keyed by field name, with the "_column" suffix
+// dropped and underscores turned into spaces, as the parser expects.
+func (m ColumnMap) Fields() (map[string]string, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	fields := make(map[string]string, len(m))
+	for key, label := range m {
+		field, ok := strings.CutSuffix(key, "_column")
+		if !ok || field == "" {
+			return nil, fmt.Errorf("column mapping %q must be named <field>_column, such as reactions_column", key)
+		}
+		fields[strings.ReplaceAll(field, "_", " ")] = label
+	}
+	return fields, nil
+}
+
+// providerDefaults holds the base URL, model, and API key variable used
+// when the config leaves them empty.
+var providerDefaults = map[string]struct{ baseURL, model, apiKeyEnv string }{
+	"openai":    {"https://api.openai.com/v1", "gpt-4o-mini", "OPENAI_API_KEY"},
+	"anthropic": {"https://api.anthropic.com/v1", "claude-sonnet-4-0", "ANTHROPIC_API_KEY"},
+}
+
+// Validate fills in defaults for empty API settings and checks that the
+// remaining values are usable, including that the API key is set.
+func (c *Config) Validate() error {
+	provider := strings.ToLower(c.API.Provider)
+	if provider == "" {
+		provider = "openai"
+	}
+	defaults, ok := providerDefaults[provider]
+	if !ok {
+		return fmt.Errorf("api.provider %q is not supported, expected openai or anthropic (default openai)", c.API.Provider)
+	}
+
+	if c.API.BaseURL == "" {
+		c.API.BaseURL = defaults.baseURL
+	}
+	if u, err := url.Parse(c.API.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("api.base_url %q is not a valid http(s) URL (leave it empty for the default %s)", c.API.BaseURL, defaults.baseURL)
+	}
+	c.API.BaseURL = strings.TrimSuffix(c.API.BaseURL, "/")
+
+	if c.API.Model == "" {
+		c.API.Model = defaults.model
+	}
+
+	// Without authentication, an empty api_key_env stays empty, so that
+	// the key for the provider's real API is not sent to another server.
+	if c.API.APIKeyEnv == "" && c.AuthRequired() {
+		c.API.APIKeyEnv = defaults.apiKeyEnv
+	}
+	if os.Getenv(c.API.APIKeyEnv) == "" && c.AuthRequired() {
+		return fmt.Errorf("environment variable %s named by api.api_key_env is not set (default %s; use --no-ai to skip the AI sections)", c.API.APIKeyEnv, defaults.apiKeyEnv)
+	}
+
+	if c.API.MaxTokens < 0 {
+		return fmt.Errorf("api.max_tokens must be positive, got %d (default 500)", c.API.MaxTokens)
+	}
+	if t := c.API.Temperature; t != nil && (*t < 0 || *t > 2) {
+		return fmt.Errorf("api.temperature must be between 0 and 2, got %g (default 0.7)", *t)
+	}
+	if c.API.Timeout < 0 {
+		return fmt.Errorf("api.timeout must be positive, got %s (default 30s)", c.API.Timeout)
+	}
+
+	return nil
+}
+
+// Merge applies the settings of override that are not zero on top of c,
+// as for the config file of a workspace. Map entries are added to copies
+// of c's maps. An override that switches the provider drops the base URL,
+// model, and API key variable of c, which belong to the other provider.
+func (c *Config) Merge(override *Config) {
+	o := override.API
+	if o.Provider != "" && !strings.EqualFold(o.Provider, c.API.Provider) {
+		c.API.BaseURL, c.API.Model, c.API.APIKeyEnv = "", "", ""
+	}
+	c.API.Provider = cmp.Or(o.Provider, c.API.Provider)
+	c.API.BaseURL = cmp.Or(o.BaseURL, c.API.BaseURL)
+	c.API.APIKeyEnv = cmp.Or(o.APIKeyEnv, c.API.APIKeyEnv)
+	if o.AuthRequired != nil {
+		c.API.AuthRequired = o.AuthRequired
+	}
+	c.API.Model = cmp.Or(o.Model, c.API.Model)
+	c.API.MaxTokens = cmp.Or(o.MaxTokens, c.API.MaxTokens)
+	if o.Temperature != nil {
+		c.API.Temperature = o.Temperature
+	}
+	c.API.MaxAttempts = cmp.Or(o.MaxAttempts, c.API.MaxAttempts)
+	c.API.RetryDelay = cmp.Or(o.RetryDelay, c.API.RetryDelay)
+	c.API.Timeout = cmp.Or(o.Timeout, c.API.Timeout)
+
+	c.Database.BusyTimeout = cmp.Or(override.Database.BusyTimeout, c.Database.BusyTimeout)
+	c.Database.JournalMode = cmp.Or(override.Database.JournalMode, c.Database.JournalMode)
+	c.Prompts.Insights = cmp.Or(override.Prompts.Insights, c.Prompts.Insights)
+	c.Prompts.NextSteps = cmp.Or(override.Prompts.NextSteps, c.Prompts.NextSteps)
+	c.Language = cmp.Or(override.Language, c.Language)
+	c.EngagementRateBasis = cmp.Or(override.EngagementRateBasis, c.EngagementRateBasis)
+	if override.Top != nil {
+		c.Top = override.Top
+	}
+
+	c.Countries = mergeMap(c.Countries, override.Countries)
+	c.Overview = mergeMap(c.Overview, override.Overview)
+	c.Posts = mergeMap(c.Posts, override.Posts)
+	c.Hashtags = mergeMap(c.Hashtags, override.Hashtags)
+}
+
+// mergeMap returns base with the entries of override added, without
+// modifying base.
+func mergeMap[M ~map[string]string](base, override M) M {
+	if len(override) == 0 {
+		return base
+	}
+	m := maps.Clone(base)
+	if m == nil {
+		m = make(M, len(override))
+	}
+	maps.Copy(m, override)
+	return m
+}
+
+// AuthRequired reports whether the API needs a key, which is the default.
+func (c *Config) AuthRequired() bool {
+	return c.API.AuthRequired == nil || *c.API.AuthRequired
+}
+
+type OverviewData struct {
+	WorkspaceName string `json:"workspace_name"`
+	Period        string `json:"period,omitempty"`
+	// PeriodStart and PeriodEnd are the first and last day of the period,
+	// if known.
+	PeriodStart    time.Time     `json:"period_start,omitzero"`
+	PeriodEnd      time.Time     `json:"period_end,omitzero"`
+	Followers      int           `json:"followers"`
+	Reach          int           `json:"reach"`
+	ReachRate      float64       `json:"reach_rate"`
+	Engagements    int           `json:"engagements"`
+	EngagementRate float64       `json:"engagement_rate"`
+	TopCountries   []CountryData `json:"top_countries"`
+	// Accounts is the per-account breakdown of multi-network workspaces.
+	Accounts []AccountData `json:"accounts,omitempty"`
+	// Months holds the totals of each month of an export with a row per
+	// month, oldest first and with Period set. The totals above are the
+	// latest month's. It is empty for an export of a single period.
+	Months []OverviewData `json:"months,omitempty"`
+}
+
+// AccountData holds the totals of one social account.
+type AccountData struct {
+	Account        string  `json:"account"`
+	Network        string  `json:"network,omitempty"`
+	Followers      int     `json:"followers"`
+	Reach          int     `json:"reach"`
+	Engagements    int     `json:"engagements"`
+	EngagementRate float64 `json:"engagement_rate"`
+}
+
+type CountryData struct {
+	Country    string  `json:"country"`
+	Users      int     `json:"users"`
+	Percentage float64 `json:"percentage"`
+	Rank       int     `json:"rank,omitempty"`
+}
+
+type CountryRanking struct {
+	Period    string        `json:"period"`
+	Countries []CountryData `json:"countries"`
+}
+
+type PostData struct {
+	Date string `json:"date"`
+	// PostedAt is Date parsed as the wall-clock time of the export's
+	// timezone. It is zero if the date is missing or unparseable.
+	PostedAt         time.Time `json:"posted_at,omitzero"`
+	SocialAccount    string    `json:"social_account"`
+	SocialNetwork    string    `json:"social_network"`
+	PostLink         string    `json:"post_link"`
+	PostText         string    `json:"post_text"`
+	PostType         string    `json:"post_type"`
+	Reach            int       `json:"reach"`
+	ReachRate        float64   `json:"reach_rate"`
+	Reactions        int       `json:"reactions"`
+	Comments         int       `json:"comments"`
+	Shares           int       `json:"shares"`
+	EngagementRate   float64   `json:"engagement_rate"`
+	LinkClicks       int       `json:"link_clicks"`
+	ClickThroughRate float64   `json:"click_through_rate"`
+}
+
+type HashtagData struct {
+	Hashtag    string  `json:"hashtag"`
+	Score      float64 `json:"score"`
+	Reach      int     `json:"reach"`
+	Reactions  int     `json:"reactions"`
+	Comments   int     `json:"comments"`
+	Shares     int     `json:"shares"`
+	VideoViews int     `json:"video_views"`
+}
+
+// HashtagChange compares a hashtag's score with the previous period. A new
+// hashtag has no previous score, and a dropped one no current score.
+type HashtagChange struct {
+	Hashtag       string  `json:"hashtag"`
+	Score         float64 `json:"score"`
+	PreviousScore float64 `json:"previous_score"`
+	Change        float64 `json:"change"`
+	New           bool    `json:"new,omitempty"`
+	Dropped       bool    `json:"dropped,omitempty"`
+}
+
+type ReportData struct {
+	Workspace string `json:"workspace"`
+	Network   string `json:"network,omitempty"`
+	Month     string `json:"month"`
+	Period    string `json:"period"`
+	// PeriodKey is the period as stored in the database, such as 2025-07.
+	PeriodKey       string `json:"-"`
+	Followers       int    `json:"followers"`
+	HasPrevious     bool   `json:"has_previous"`
+	PreviousPeriod  string `json:"previous_period,omitempty"`
+	FollowersChange int    `json:"followers_change"`
+	// FollowersChangePct is FollowersChange in percent of the previous
+	// period's followers. It is nil without a previous period or if that
+	// had no followers.
+	FollowersChangePct   *float64 `json:"followers_change_pct,omitempty"`
+	Reach                int      `json:"reach"`
+	ReachChange          float64  `json:"reach_change"`
+	ReachRate            float64  `json:"reach_rate"`
+	ReachRateChange      float64  `json:"reach_rate_change"`
+	Engagements          int      `json:"engagements"`
+	EngagementsChange    float64  `json:"engagements_change"`
+	EngagementRate       float64  `json:"engagement_rate"`
+	EngagementRateChange float64  `json:"engagement_rate_change"`
+	// RateBasis is "reach" or "followers" if the engagement rates were
+	// recomputed as engagements per reach or follower, and empty if they
+	// are Publer's.
+	RateBasis     string         `json:"engagement_rate_basis,omitempty"`
+	Derived       DerivedMetrics `json:"derived"`
+	YearOverYear  *Comparison    `json:"year_over_year,omitempty"`
+	History       []OverviewData `json:"history,omitempty"`
+	RankBy        string         `json:"rank_by"`
+	HashtagRankBy string         `json:"hashtag_rank_by"`
+	TopPosts      []PostData     `json:"top_posts"`
+	TopHashtags   []HashtagData  `json:"top_hashtags"`
+	// HashtagRisers and HashtagFallers are the hashtags whose score changed
+	// the most since the previous period, biggest change first.
+	HashtagRisers  []HashtagChange `json:"hashtag_risers,omitempty"`
+	HashtagFallers []HashtagChange `json:"hashtag_fallers,omitempty"`
+	TopCountries   []CountryData   `json:"top_countries"`
+	Accounts       []AccountData   `json:"accounts,omitempty"`
+	// BestTimes are the weekday and hour slots whose posts had the highest
+	// average engagement. It is empty if no post has a usable date.
+	BestTimes []PostingSlot `json:"best_posting_times,omitempty"`
+	AIWarning string        `json:"ai_warning,omitempty"`
+	Insights  string        `json:"insights"`
+	NextSteps string        `json:"next_steps"`
+	// Decimals is the number of decimal places the templates show for
+	// percentages and averages.
+	Decimals int `json:"-"`
+	// Language is the language the AI texts are requested in, such as
+	// "German". Empty keeps the language of the prompts.
+	Language string `json:"language,omitempty"`
+}
+
+// DerivedMetrics are averages computed from all posts of a period, not only
+// the top posts. Values whose denominator is zero stay zero.
+type DerivedMetrics struct {
+	Posts             int     `json:"posts"`
+	AvgReactions      float64 `json:"avg_reactions_per_post"`
+	AvgComments       float64 `json:"avg_comments_per_post"`
+	AvgShares         float64 `json:"avg_shares_per_post"`
+	ReachPerFollower  float64 `json:"reach_per_follower"`
+	PostsPerDay       float64 `json:"posts_per_day"`
+	EngagementsPerDay float64 `json:"engagements_per_day"`
+}
+
+// PostingSlot holds the average reactions and engagements of the posts
+// published in one hour of one weekday.
+type PostingSlot struct {
+	Weekday        string  `json:"weekday"`
+	Hour           int     `json:"hour"`
+	Posts          int     `json:"posts"`
+	AvgReactions   float64 `json:"avg_reactions"`
+	AvgEngagements float64 `json:"avg_engagements"`
+}
+
+type RangeReportData struct {
+	Workspace         string         `json:"workspace"`
+	Since             string         `json:"since"`
+	Until             string         `json:"until"`
+	Periods           []OverviewData `json:"periods"`
+	Followers         int            `json:"followers"`
+	FollowersChange   int            `json:"followers_change"`
+	TotalReach        int            `json:"total_reach"`
+	TotalEngagements  int            `json:"total_engagements"`
+	AvgReach          float64        `json:"avg_reach"`
+	AvgEngagements    float64        `json:"avg_engagements"`
+	AvgReachRate      float64        `json:"avg_reach_rate"`
+	AvgEngagementRate float64        `json:"avg_engagement_rate"`
+	RankBy            string         `json:"rank_by"`
+	HashtagRankBy     string         `json:"hashtag_rank_by"`
+	TopPosts          []PostData     `json:"top_posts"`
+	TopHashtags       []HashtagData  `json:"top_hashtags"`
+	// Decimals is the number of decimal places the template shows for
+	// percentages and averages.
+	Decimals int `json:"-"`
+}
+
+// ComparisonReportData compares two stored periods of a workspace, From
+// and To. Change holds the changes from From to To.
+type ComparisonReportData struct {
+	Workspace      string          `json:"workspace"`
+	From           OverviewData    `json:"from"`
+	To             OverviewData    `json:"to"`
+	Change         Comparison      `json:"change"`
+	HashtagRisers  []HashtagChange `json:"hashtag_risers,omitempty"`
+	HashtagFallers []HashtagChange `json:"hashtag_fallers,omitempty"`
+	// RateBasis is the denominator the engagement rates were recomputed
+	// on, or empty for Publer's rates.
+	RateBasis string `json:"rate_basis,omitempty"`
+	Decimals  int    `json:"-"`
+}
+
+type Comparison struct {
+	Period          string `json:"period"`
+	Month           string `json:"month"`
+	FollowersChange int    `json:"followers_change"`
+	// FollowersChangePct is nil if the other period had no followers.
+	FollowersChangePct   *float64 `json:"followers_change_pct,omitempty"`
+	ReachChange          float64  `json:"reach_change"`
+	ReachRateChange      float64  `json:"reach_rate_change"`
+	EngagementsChange    float64  `json:"engagements_change"`
+	EngagementRateChange float64  `json:"engagement_rate_change"`
+}
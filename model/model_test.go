@@ -0,0 +1,134 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestValidate(t *testing.T) {
+	t.Setenv("PUBLER_TEST_KEY", "secret")
+	noAuth := false
+	hot := 2.5
+
+	tests := []struct {
+		name    string
+		config  func(c *Config)
+		baseURL string
+		model   string
+		keyEnv  string
+		err     string
+	}{
+		{
+			name:    "openai defaults",
+			config:  func(c *Config) { c.API.APIKeyEnv = "PUBLER_TEST_KEY" },
+			baseURL: "https://api.openai.com/v1", model: "gpt-4o-mini", keyEnv: "PUBLER_TEST_KEY",
+		},
+		{
+			name:    "anthropic defaults",
+			config:  func(c *Config) { c.API.Provider = "Anthropic"; c.API.APIKeyEnv = "PUBLER_TEST_KEY" },
+			baseURL: "https://api.anthropic.com/v1", model: "claude-sonnet-4-0", keyEnv: "PUBLER_TEST_KEY",
+		},
+		{
+			name: "local server without auth",
+			config: func(c *Config) {
+				c.API.BaseURL = "http://localhost:11434/v1/"
+				c.API.Model = "llama3"
+				c.API.AuthRequired = &noAuth
+			},
+			baseURL: "http://localhost:11434/v1", model: "llama3",
+		},
+		{name: "unknown provider", config: func(c *Config) { c.API.Provider = "gemini" }, err: "not supported"},
+		{name: "invalid URL", config: func(c *Config) { c.API.BaseURL = "localhost:8080" }, err: "not a valid http(s) URL"},
+		{name: "missing key", config: func(c *Config) { c.API.APIKeyEnv = "PUBLER_TEST_UNSET_KEY" }, err: "PUBLER_TEST_UNSET_KEY"},
+		{name: "negative max tokens", config: func(c *Config) { c.API.APIKeyEnv = "PUBLER_TEST_KEY"; c.API.MaxTokens = -1 }, err: "max_tokens"},
+		{name: "temperature too high", config: func(c *Config) { c.API.APIKeyEnv = "PUBLER_TEST_KEY"; c.API.Temperature = &hot }, err: "temperature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Config
+			tt.config(&c)
+			err := c.Validate()
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("Validate error = %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.API.BaseURL != tt.baseURL || c.API.Model != tt.model || c.API.APIKeyEnv != tt.keyEnv {
+				t.Errorf("API = %s, %s, %s, want %s, %s, %s", c.API.BaseURL, c.API.Model, c.API.APIKeyEnv, tt.baseURL, tt.model, tt.keyEnv)
+			}
+		})
+	}
+}
+
+func TestColumnMapFields(t *testing.T) {
+	tests := []struct {
+		m    ColumnMap
+		want map[string]string
+		err  bool
+	}{
+		{nil, nil, false},
+		{ColumnMap{"reactions_column": "Likes", "post_type_column": "Kind"}, map[string]string{"reactions": "Likes", "post type": "Kind"}, false},
+		{ColumnMap{"reactions": "Likes"}, nil, true},
+		{ColumnMap{"_column": "Likes"}, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := tt.m.Fields()
+		if (err != nil) != tt.err {
+			t.Errorf("Fields(%v) error = %v", tt.m, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Fields(%v) = %v, want %v", tt.m, got, tt.want)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	top := 3
+	base := &Config{Language: "de", Countries: map[string]string{"USA": "United States"}}
+	base.API.Provider = "openai"
+	base.API.BaseURL = "https://proxy.example.com/v1"
+	base.API.Model = "gpt-4o"
+	base.API.MaxTokens = 800
+
+	override := &Config{Top: &top, Countries: map[string]string{"UK": "United Kingdom"}}
+	override.API.Provider = "anthropic"
+	override.API.MaxTokens = 0
+
+	merged := *base
+	merged.Merge(override)
+
+	if merged.API.Provider != "anthropic" || merged.API.BaseURL != "" || merged.API.Model != "" {
+		t.Errorf("switching the provider kept %s, %s", merged.API.BaseURL, merged.API.Model)
+	}
+	if merged.API.MaxTokens != 800 || merged.Language != "de" {
+		t.Errorf("zero override fields replaced the base: %d, %q", merged.API.MaxTokens, merged.Language)
+	}
+	if merged.Top == nil || *merged.Top != 3 {
+		t.Errorf("top = %v, want 3", merged.Top)
+	}
+	if want := map[string]string{"USA": "United States", "UK": "United Kingdom"}; !reflect.DeepEqual(merged.Countries, want) {
+		t.Errorf("countries = %v, want %v", merged.Countries, want)
+	}
+	if len(base.Countries) != 1 {
+		t.Errorf("Merge modified the base's map: %v", base.Countries)
+	}
+}
+
+func TestConfigYAML(t *testing.T) {
+	data := "api:\n  base_url: \"https://api.openai.com/v1\"\n  api_key_env: \"OPENAI_API_KEY\"\n  model: \"gpt-4o-mini\"\n"
+	var c Config
+	if err := yaml.Unmarshal([]byte(data), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.API.BaseURL != "https://api.openai.com/v1" || c.API.APIKeyEnv != "OPENAI_API_KEY" || c.API.Model != "gpt-4o-mini" {
+		t.Errorf("API = %+v", c.API)
+	}
+}
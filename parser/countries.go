@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/christophberger/publer-analytics-report/model"
+)
+
+// countryAliases maps lowercase aliases and ISO 3166 codes to the country
+// names Publer uses most of the time. Options.CountryAliases extends it.
+var countryAliases = map[string]string{
+	"us":                       "United States",
+	"usa":                      "United States",
+	"u.s.":                     "United States",
+	"u.s.a.":                   "United States",
+	"united states of america": "United States",
+	"uk":                       "United Kingdom",
+	"u.k.":                     "United Kingdom",
+	"gb":                       "United Kingdom",
+	"gbr":                      "United Kingdom",
+	"great britain":            "United Kingdom",
+	"ch":                       "Switzerland",
+	"che":                      "Switzerland",
+	"schweiz":                  "Switzerland",
+	"de":                       "Germany",
+	"deu":                      "Germany",
+	"deutschland":              "Germany",
+	"at":                       "Austria",
+	"aut":                      "Austria",
+	"österreich":               "Austria",
+	"fr":                       "France",
+	"fra":                      "France",
+	"it":                       "Italy",
+	"ita":                      "Italy",
+	"es":                       "Spain",
+	"esp":                      "Spain",
+	"nl":                       "Netherlands",
+	"nld":                      "Netherlands",
+	"the netherlands":          "Netherlands",
+	"holland":                  "Netherlands",
+	"be":                       "Belgium",
+	"bel":                      "Belgium",
+	"lu":                       "Luxembourg",
+	"lux":                      "Luxembourg",
+	"gr":                       "Greece",
+	"grc":                      "Greece",
+	"pt":                       "Portugal",
+	"prt":                      "Portugal",
+	"pl":                       "Poland",
+	"pol":                      "Poland",
+	"se":                       "Sweden",
+	"swe":                      "Sweden",
+	"ie":                       "Ireland",
+	"irl":                      "Ireland",
+	"in":                       "India",
+	"ind":                      "India",
+	"ca":                       "Canada",
+	"can":                      "Canada",
+	"au":                       "Australia",
+	"aus":                      "Australia",
+	"br":                       "Brazil",
+	"bra":                      "Brazil",
+	"cn":                       "China",
+	"chn":                      "China",
+	"jp":                       "Japan",
+	"jpn":                      "Japan",
+	"ae":                       "United Arab Emirates",
+	"uae":                      "United Arab Emirates",
+}
+
+// normalizeCountry returns the canonical name for a country alias, looking
+// at the custom aliases first. Unknown names are returned unchanged.
+func normalizeCountry(name string, custom map[string]string) string {
+	key := strings.ToLower(strings.TrimSpace(name))
+	for alias, canonical := range custom {
+		if strings.ToLower(strings.TrimSpace(alias)) == key {
+			return canonical
+		}
+	}
+	if canonical, ok := countryAliases[key]; ok {
+		return canonical
+	}
+	return name
+}
+
+// mergeCountries sums the users of countries that normalize to the same
+// name, keeping the position of the first occurrence.
+func mergeCountries(countries []model.CountryData, custom map[string]string) []model.CountryData {
+	var merged []model.CountryData
+	index := make(map[string]int)
+	for _, c := range countries {
+		c.Country = normalizeCountry(c.Country, custom)
+		if i, ok := index[c.Country]; ok {
+			merged[i].Users += c.Users
+			continue
+		}
+		index[c.Country] = len(merged)
+		merged = append(merged, c)
+	}
+	return merged
+}
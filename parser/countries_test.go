@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/christophberger/publer-analytics-report/model"
+)
+
+func TestNormalizeCountry(t *testing.T) {
+	custom := map[string]string{"Suisse": "Switzerland", " usa ": "America"}
+	tests := []struct {
+		name   string
+		custom map[string]string
+		want   string
+	}{
+		{"United States", nil, "United States"},
+		{"USA", nil, "United States"},
+		{"US", nil, "United States"},
+		{" u.s.a. ", nil, "United States"},
+		{"United States of America", nil, "United States"},
+		{"GB", nil, "United Kingdom"},
+		{"Deutschland", nil, "Germany"},
+		{"Österreich", nil, "Austria"},
+		{"Narnia", nil, "Narnia"},
+		{"suisse", custom, "Switzerland"},
+		// Custom aliases take precedence over the built-in ones.
+		{"USA", custom, "America"},
+		{"US", custom, "United States"},
+	}
+	for _, tt := range tests {
+		if got := normalizeCountry(tt.name, tt.custom); got != tt.want {
+			t.Errorf("normalizeCountry(%q, %v) = %q, want %q", tt.name, tt.custom, got, tt.want)
+		}
+	}
+}
+
+func TestMergeCountries(t *testing.T) {
+	countries := []model.CountryData{
+		{Country: "United States", Users: 100},
+		{Country: "Germany", Users: 50},
+		{Country: "USA", Users: 30},
+		{Country: "US", Users: 5},
+		{Country: "DE", Users: 20},
+	}
+	want := []model.CountryData{
+		{Country: "United States", Users: 135},
+		{Country: "Germany", Users: 70},
+	}
+	if got := mergeCountries(countries, nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeCountries = %+v, want %+v", got, want)
+	}
+}
+
+func TestCountryAliasesInOverview(t *testing.T) {
+	csv := "Workspace Name,Followers,Reach\nAcme,100,50\n\nTop Countries,Users\nUnited States,60\nGermany,25\nUSA,15\nSuisse,25\n"
+	overview, _, err := ReadOverview(strings.NewReader(csv), "overview.csv", Options{CountryAliases: map[string]string{"Suisse": "Switzerland"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.CountryData{
+		{Country: "United States", Users: 75, Percentage: 60},
+		{Country: "Germany", Users: 25, Percentage: 20},
+		{Country: "Switzerland", Users: 25, Percentage: 20},
+	}
+	if !reflect.DeepEqual(overview.TopCountries, want) {
+		t.Errorf("countries = %+v, want %+v", overview.TopCountries, want)
+	}
+}
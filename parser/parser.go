@@ -0,0 +1,899 @@
+// Package parser reads the tables inside Publer Analytics CSV exports.
+package parser
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/christophberger/publer-analytics-report/model"
+)
+
+// numberFormat holds the thousands and decimal separators of the numbers
+// in an export.
+type numberFormat struct{ thousands, decimal string }
+
+var (
+	pointDecimal = numberFormat{",", "."}
+	commaDecimal = numberFormat{".", ","}
+)
+
+// numbersFor returns the number format of an export with the given field
+// delimiter. Publer writes ";"-delimited exports for the locales that use
+// a decimal comma, such as "1.234,5"; all others use a decimal point.
+func numbersFor(delim rune) numberFormat {
+	if delim == ';' {
+		return commaDecimal
+	}
+	return pointDecimal
+}
+
+// cleanNumber returns s without spaces, a trailing "%", and thousands
+// separators, and with a decimal point. A thousands separator that does
+// not start a group of three digits, as in "3,5" for a decimal-point
+// export, makes the number ambiguous, so it is an error rather than being
+// dropped.
+func cleanNumber(s string, f numberFormat) (string, error) {
+	clean := strings.TrimSpace(s)
+	clean = strings.TrimSuffix(clean, "%")
+	clean = strings.NewReplacer(" ", "", "\u00a0", "", "\u202f", "").Replace(clean)
+
+	whole, frac, _ := strings.Cut(clean, f.decimal)
+	if strings.Contains(frac, f.thousands) {
+		return "", fmt.Errorf("invalid number %q", s)
+	}
+	if strings.Contains(whole, f.thousands) {
+		groups := strings.Split(strings.TrimLeft(whole, "+-"), f.thousands)
+		for i, g := range groups {
+			if len(g) == 0 || len(g) > 3 || (i > 0 && len(g) != 3) {
+				return "", fmt.Errorf("invalid number %q", s)
+			}
+		}
+	}
+	clean = strings.ReplaceAll(clean, f.thousands, "")
+	return strings.ReplaceAll(clean, f.decimal, "."), nil
+}
+
+func parseMetric(s string, f numberFormat) (int, error) {
+	clean, err := cleanNumber(s, f)
+	if err != nil {
+		return 0, err
+	}
+	if clean == "" || clean == "-" {
+		return 0, nil
+	}
+
+	multiplier := 1.0
+	switch clean[len(clean)-1] {
+	case 'k', 'K':
+		multiplier = 1e3
+	case 'm', 'M':
+		multiplier = 1e6
+	case 'b', 'B':
+		multiplier = 1e9
+	}
+	if multiplier != 1 {
+		clean = clean[:len(clean)-1]
+	}
+
+	n, err := parseFinite(clean, s)
+	if err != nil {
+		return 0, err
+	}
+	n = math.Round(n * multiplier)
+	if n < math.MinInt64 || n >= math.MaxInt64 {
+		return 0, fmt.Errorf("number %q out of range", s)
+	}
+	return int(n), nil
+}
+
+func parseFloatLoose(s string, f numberFormat) (float64, error) {
+	clean, err := cleanNumber(s, f)
+	if err != nil {
+		return 0, err
+	}
+	if clean == "" || clean == "-" {
+		return 0, nil
+	}
+	return parseFinite(clean, s)
+}
+
+// parseFinite parses the cleaned number clean of the cell s. Unlike
+// strconv.ParseFloat, it rejects "NaN" and "Inf", which no metric can be.
+func parseFinite(clean, s string) (float64, error) {
+	f, err := strconv.ParseFloat(clean, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return f, nil
+}
+
+type csvFile struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (f *csvFile) Close() error {
+	var err error
+	for _, c := range f.closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// openCSV opens a CSV export and transparently decompresses it if it starts
+// with the gzip magic bytes, regardless of its extension.
+func openCSV(filename string) (io.ReadCloser, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := decompress(file, filename)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &csvFile{Reader: rc, closers: []io.Closer{rc, file}}, nil
+}
+
+// decompress returns r, gunzipped if it starts with the gzip magic bytes.
+// Closing the result does not close r.
+func decompress(r io.Reader, name string) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return gz, nil
+	}
+	return io.NopCloser(br), nil
+}
+
+// Options controls how the readers parse a CSV export.
+type Options struct {
+	// Delimiter is the field separator. Zero detects it from the first
+	// lines of each file.
+	Delimiter rune
+	// CountryAliases maps additional country names or codes to the name
+	// they are merged into, on top of the built-in aliases.
+	CountryAliases map[string]string
+	// OverviewColumns, PostColumns, and HashtagColumns map field names,
+	// such as "reactions" or "score", to the header label of the column
+	// that holds them. They override the detection by header name for
+	// exports whose labels the readers do not know.
+	OverviewColumns map[string]string
+	PostColumns     map[string]string
+	HashtagColumns  map[string]string
+	// StrictRows fails a read on a data row whose number of fields differs
+	// from the header's, instead of skipping or padding it.
+	StrictRows bool
+}
+
+// sniffSize is how much of a file is inspected to detect the delimiter.
+const sniffSize = 4096
+
+const utf8BOM = "\ufeff"
+
+var delimiterCandidates = []rune{',', ';', '\t'}
+
+func newCSVReader(r io.Reader, opts Options) *csv.Reader {
+	br := bufio.NewReaderSize(r, sniffSize)
+	// Excel adds a UTF-8 byte order mark when it re-saves an export.
+	if b, err := br.Peek(len(utf8BOM)); err == nil && string(b) == utf8BOM {
+		br.Discard(len(utf8BOM))
+	}
+
+	delim := opts.Delimiter
+	if delim == 0 {
+		head, _ := br.Peek(sniffSize)
+		delim = sniffDelimiter(head)
+	}
+
+	reader := csv.NewReader(br)
+	reader.Comma = delim
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	return reader
+}
+
+// sniffDelimiter picks the candidate that occurs most often outside quotes
+// in the first table lines, skipping blank lines and "#" comments. It
+// defaults to a comma.
+func sniffDelimiter(head []byte) rune {
+	counts := map[rune]int{}
+	lines := 0
+	for _, line := range strings.Split(string(head), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		inQuotes := false
+		for _, c := range line {
+			if c == '"' {
+				inQuotes = !inQuotes
+			} else if !inQuotes && slices.Contains(delimiterCandidates, c) {
+				counts[c]++
+			}
+		}
+		if lines++; lines == 5 {
+			break
+		}
+	}
+
+	best := ','
+	for _, c := range delimiterCandidates {
+		if counts[c] > counts[best] {
+			best = c
+		}
+	}
+	return best
+}
+
+func field(record []string, i int) string {
+	if i < len(record) {
+		return record[i]
+	}
+	return ""
+}
+
+// columnAliases maps column labels that Publer uses in some tables to the
+// canonical name the readers look up.
+var columnAliases = map[string]string{
+	"likes":   "reactions",
+	"account": "social account",
+	"network": "social network",
+	"period":  "month",
+}
+
+// columnName returns the canonical name of a column label: lowercase, with
+// single spaces, and without a trailing "(%)" unit.
+func columnName(label string) string {
+	name := strings.ToLower(strings.Join(strings.Fields(label), " "))
+	name = strings.TrimSpace(strings.TrimSuffix(name, "(%)"))
+	if alias, ok := columnAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// header maps the canonical column names of a table's header row to their
+// index. If a name occurs twice, the first column wins.
+type header map[string]int
+
+func newHeader(rec []string) header {
+	h := make(header, len(rec))
+	for i, label := range rec {
+		name := columnName(label)
+		if _, dup := h[name]; !dup {
+			h[name] = i
+		}
+	}
+	return h
+}
+
+func (h header) has(name string) bool {
+	_, ok := h[name]
+	return ok
+}
+
+// get returns the named column of record, or "" if the table or the record
+// has no such column.
+func (h header) get(record []string, name string) string {
+	i, ok := h[name]
+	if !ok {
+		return ""
+	}
+	return field(record, i)
+}
+
+// override points the fields in columns at the columns with the given
+// header labels. A configured label that the table lacks is an error rather
+// than a silent fallback.
+func (h header) override(columns map[string]string) error {
+	for field, label := range columns {
+		i, ok := h[columnName(label)]
+		if !ok {
+			return fmt.Errorf("column %q configured for %s not found", label, field)
+		}
+		h[field] = i
+	}
+	return nil
+}
+
+// readHeader reads records until it finds the header row, recognized by a
+// column named marker, so that the readers depend neither on the number of
+// preamble lines before the table nor on the order of its columns. The
+// configured columns replace the detected ones, and may name the marker
+// column, too. It also returns the number of fields of the header row.
+func readHeader(reader *csv.Reader, marker string, columns map[string]string) (header, int, error) {
+	field := columnName(marker)
+	if label, ok := columns[field]; ok {
+		marker = label
+	}
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			return nil, 0, fmt.Errorf("no header row with a %q column found", marker)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		h := newHeader(rec)
+		if h.has(columnName(marker)) {
+			return h, len(rec), h.override(columns)
+		}
+	}
+}
+
+// Warning describes a cell that does not hold a number and was read as
+// zero, or a data row that was skipped.
+type Warning struct {
+	File   string
+	Line   int
+	Column string
+	Value  string
+	// Skipped marks a skipped row, and Reason says why. Column and Value
+	// are empty then.
+	Skipped bool
+	Reason  string
+}
+
+func (w Warning) String() string {
+	if w.Skipped {
+		return fmt.Sprintf("%s: line %d: row skipped: %s", w.File, w.Line, w.Reason)
+	}
+	return fmt.Sprintf("%s: line %d: %s: invalid number %q read as 0", w.File, w.Line, w.Column, w.Value)
+}
+
+// SkippedRows returns the number of warnings about skipped rows.
+func SkippedRows(warnings []Warning) int {
+	n := 0
+	for _, w := range warnings {
+		if w.Skipped {
+			n++
+		}
+	}
+	return n
+}
+
+// cells parses the numeric cells of a file's rows and collects a warning
+// for each one that does not hold a number, instead of silently reading it
+// as zero.
+type cells struct {
+	file     string
+	reader   *csv.Reader
+	numbers  numberFormat
+	warnings []Warning
+}
+
+func newCells(reader *csv.Reader, file string) *cells {
+	return &cells{file: file, reader: reader, numbers: numbersFor(reader.Comma)}
+}
+
+func (c *cells) warn(h header, name, value string) {
+	line, _ := c.reader.FieldPos(h[name])
+	c.warnings = append(c.warnings, Warning{File: c.file, Line: line, Column: name, Value: strings.TrimSpace(value)})
+}
+
+// skip records a warning for the row that was just read.
+func (c *cells) skip(reason string) {
+	line, _ := c.reader.FieldPos(0)
+	c.warnings = append(c.warnings, Warning{File: c.file, Line: line, Skipped: true, Reason: reason})
+}
+
+// readErr handles an error of reading a data row: with strict set, it
+// returns the error, otherwise it records the row as skipped.
+func (c *cells) readErr(err error, strict bool) error {
+	var perr *csv.ParseError
+	if strict || !errors.As(err, &perr) {
+		return fmt.Errorf("%s: %w", c.file, err)
+	}
+	c.warnings = append(c.warnings, Warning{File: c.file, Line: perr.StartLine, Skipped: true, Reason: perr.Err.Error()})
+	return nil
+}
+
+// checkWidth returns an error if rec has not the width of the header row.
+// Blank rows carry no data and are exempt.
+func (c *cells) checkWidth(rec []string, width int) error {
+	if len(rec) == width || isBlankRecord(rec) {
+		return nil
+	}
+	line, _ := c.reader.FieldPos(0)
+	return fmt.Errorf("%s: line %d: %d fields, expected %d as in the header row", c.file, line, len(rec), width)
+}
+
+func (c *cells) metric(h header, rec []string, name string) int {
+	s := h.get(rec, name)
+	n, err := parseMetric(s, c.numbers)
+	if err != nil {
+		c.warn(h, name, s)
+	}
+	return n
+}
+
+func (c *cells) float(h header, rec []string, name string) float64 {
+	s := h.get(rec, name)
+	f, err := parseFloatLoose(s, c.numbers)
+	if err != nil {
+		c.warn(h, name, s)
+	}
+	return f
+}
+
+func isBlankRecord(record []string) bool {
+	for _, f := range record {
+		if strings.TrimSpace(f) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func parseOverviewRow(h header, rec []string, f numberFormat) (*model.OverviewData, error) {
+	data := &model.OverviewData{WorkspaceName: strings.TrimSpace(h.get(rec, "workspace name"))}
+	if data.WorkspaceName == "" {
+		return nil, fmt.Errorf("overview row has no workspace name")
+	}
+
+	var err error
+	if data.Followers, err = parseMetric(h.get(rec, "followers"), f); err != nil {
+		return nil, fmt.Errorf("followers: %w", err)
+	}
+	if data.Reach, err = parseMetric(h.get(rec, "reach"), f); err != nil {
+		return nil, fmt.Errorf("reach: %w", err)
+	}
+	if data.ReachRate, err = parseFloatLoose(h.get(rec, "reach rate"), f); err != nil {
+		return nil, fmt.Errorf("reach rate: %w", err)
+	}
+	if data.Engagements, err = parseMetric(h.get(rec, "engagements"), f); err != nil {
+		return nil, fmt.Errorf("engagements: %w", err)
+	}
+	if data.EngagementRate, err = parseFloatLoose(h.get(rec, "engagement rate"), f); err != nil {
+		return nil, fmt.Errorf("engagement rate: %w", err)
+	}
+
+	return data, nil
+}
+
+// ReadOverviewFile reads the overview export in filename. See ReadOverview.
+func ReadOverviewFile(filename string, opts Options) (*model.OverviewData, []Warning, error) {
+	file, err := openCSV(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	return readOverview(file, filename, opts)
+}
+
+// ReadOverview reads the totals, countries, and accounts of an overview
+// export from r, which may be gzip-compressed. The name identifies the
+// export in errors and warnings. The warnings list the cells of the
+// accounts that were read as zero; an invalid number in the totals is an
+// error.
+func ReadOverview(r io.Reader, name string, opts Options) (*model.OverviewData, []Warning, error) {
+	rc, err := decompress(r, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rc.Close()
+
+	return readOverview(rc, name, opts)
+}
+
+func readOverview(r io.Reader, filename string, opts Options) (*model.OverviewData, []Warning, error) {
+	reader := newCSVReader(r, opts)
+	c := newCells(reader, filename)
+
+	h, width, err := readHeader(reader, "Workspace Name", opts.OverviewColumns)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	var rec []string
+	for {
+		rec, err = reader.Read()
+		if err == io.EOF {
+			return nil, nil, fmt.Errorf("%s: no data row after the \"Workspace Name\" header", filename)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		if !isBlankRecord(rec) {
+			break
+		}
+	}
+	// The tables below the totals are ragged in Publer's own exports, so
+	// only the totals rows are checked.
+	if opts.StrictRows {
+		if err := c.checkWidth(rec, width); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	data, err := parseOverviewRow(h, rec, c.numbers)
+	if err != nil {
+		line, _ := reader.FieldPos(0)
+		return nil, nil, fmt.Errorf("%s: line %d: %w: %q", filename, line, err, strings.Join(rec, ","))
+	}
+
+	// An export of several months has a month column and a totals row per
+	// month.
+	var section overviewSection
+	var months []model.OverviewData
+	if h.has("month") {
+		if data.Period, err = parseMonth(h.get(rec, "month")); err != nil {
+			line, _ := reader.FieldPos(h["month"])
+			return nil, nil, fmt.Errorf("%s: line %d: %w", filename, line, err)
+		}
+		months = append(months, *data)
+		section = monthsSection
+	}
+
+	// The tables below the totals are recognized by their header rows. A
+	// row that does not fit the current table ends it.
+	for {
+		rec, err = reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if err := c.readErr(err, opts.StrictRows); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if next := newHeader(rec); next.has("top countries") {
+			h, section = next, countriesSection
+			continue
+		} else if next.has("social account") && next.has("followers") {
+			h, section = next, accountsSection
+			continue
+		}
+
+		switch section {
+		case monthsSection:
+			// A row without a month ends the table.
+			period, err := parseMonth(h.get(rec, "month"))
+			if err != nil {
+				section = noSection
+				continue
+			}
+			if opts.StrictRows {
+				if err := c.checkWidth(rec, width); err != nil {
+					return nil, nil, err
+				}
+			}
+			month, err := parseOverviewRow(h, rec, c.numbers)
+			if err != nil {
+				line, _ := reader.FieldPos(0)
+				return nil, nil, fmt.Errorf("%s: line %d: %w: %q", filename, line, err, strings.Join(rec, ","))
+			}
+			month.Period = period
+			months = append(months, *month)
+		case countriesSection:
+			country, ok := parseCountryRow(h, rec, c.numbers)
+			if !ok {
+				section = noSection
+				continue
+			}
+			data.TopCountries = append(data.TopCountries, country)
+		case accountsSection:
+			account, ok := parseAccountRow(h, rec, c)
+			if !ok {
+				section = noSection
+				continue
+			}
+			data.Accounts = append(data.Accounts, account)
+		}
+	}
+
+	if len(months) > 1 {
+		if data, err = latestMonth(data, months); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+
+	data.TopCountries = mergeCountries(data.TopCountries, opts.CountryAliases)
+	total := 0
+	for _, c := range data.TopCountries {
+		total += c.Users
+	}
+	if total > 0 {
+		for i := range data.TopCountries {
+			data.TopCountries[i].Percentage = float64(data.TopCountries[i].Users) * 100.0 / float64(total)
+		}
+	}
+
+	return data, c.warnings, nil
+}
+
+type overviewSection int
+
+const (
+	noSection overviewSection = iota
+	monthsSection
+	countriesSection
+	accountsSection
+)
+
+// latestMonth returns the totals of the latest of the months, with the top
+// countries and accounts of data, which cover the whole export, and with
+// all months in Months.
+func latestMonth(data *model.OverviewData, months []model.OverviewData) (*model.OverviewData, error) {
+	slices.SortFunc(months, func(a, b model.OverviewData) int { return strings.Compare(a.Period, b.Period) })
+	for i := 1; i < len(months); i++ {
+		if months[i].Period == months[i-1].Period {
+			return nil, fmt.Errorf("month %s has more than one totals row", months[i].Period)
+		}
+	}
+	latest := months[len(months)-1]
+	latest.TopCountries, latest.Accounts = data.TopCountries, data.Accounts
+	latest.Months = months
+	return &latest, nil
+}
+
+// monthLayouts are the formats of the month column of multi-month exports.
+var monthLayouts = []string{
+	"2006-01",
+	"Jan 2006",
+	"January 2006",
+	"01/2006",
+	"2006/01",
+	"2 Jan 2006",
+	"2006-01-02",
+}
+
+// parseMonth returns the month of s as YYYY-MM. A date range, such as
+// "1 Jul 2025 - 31 Jul 2025", stands for the month it starts in.
+func parseMonth(s string) (string, error) {
+	start, _, _ := strings.Cut(s, " - ")
+	start = strings.TrimSpace(start)
+	for _, layout := range monthLayouts {
+		if t, err := time.Parse(layout, start); err == nil {
+			return t.Format("2006-01"), nil
+		}
+	}
+	return "", fmt.Errorf("month: unknown month %q", strings.TrimSpace(s))
+}
+
+func parseCountryRow(h header, rec []string, f numberFormat) (model.CountryData, bool) {
+	name := strings.TrimSpace(h.get(rec, "top countries"))
+	if name == "" || strings.HasPrefix(name, "Top") {
+		return model.CountryData{}, false
+	}
+	users := h.get(rec, "users")
+	if strings.TrimSpace(users) == "" {
+		return model.CountryData{}, false
+	}
+	u, err := parseMetric(users, f)
+	if err != nil {
+		return model.CountryData{}, false
+	}
+	return model.CountryData{Country: name, Users: u}, true
+}
+
+// parseAccountRow reads a row of the per-account breakdown that
+// multi-network workspaces have below the totals.
+func parseAccountRow(h header, rec []string, c *cells) (model.AccountData, bool) {
+	account := model.AccountData{
+		Account: strings.TrimSpace(h.get(rec, "social account")),
+		Network: strings.TrimSpace(h.get(rec, "social network")),
+	}
+	if account.Account == "" {
+		return account, false
+	}
+
+	// A row without a follower count is not an account and ends the table.
+	var err error
+	if account.Followers, err = parseMetric(h.get(rec, "followers"), c.numbers); err != nil {
+		return account, false
+	}
+	account.Reach = c.metric(h, rec, "reach")
+	account.Engagements = c.metric(h, rec, "engagements")
+	account.EngagementRate = c.float(h, rec, "engagement rate")
+	return account, true
+}
+
+// ReadPostInsightsFile reads the post insights export in filename. See
+// ReadPostInsights.
+func ReadPostInsightsFile(filename string, opts Options) ([]model.PostData, []Warning, error) {
+	file, err := openCSV(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	return readPostInsights(file, filename, opts)
+}
+
+// ReadPostInsights reads the posts of a post insights export from r, which
+// may be gzip-compressed. The name identifies the export in errors and
+// warnings. The warnings list the cells that were read as zero.
+func ReadPostInsights(r io.Reader, name string, opts Options) ([]model.PostData, []Warning, error) {
+	rc, err := decompress(r, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rc.Close()
+
+	return readPostInsights(rc, name, opts)
+}
+
+func readPostInsights(r io.Reader, filename string, opts Options) ([]model.PostData, []Warning, error) {
+	reader := newCSVReader(r, opts)
+	c := newCells(reader, filename)
+
+	h, width, err := readHeader(reader, "Post type", opts.PostColumns)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	var posts []model.PostData
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if err := c.readErr(err, opts.StrictRows); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if opts.StrictRows {
+			if err := c.checkWidth(record, width); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		// Later columns are optional because Publer sometimes trims
+		// trailing empty cells, but a post row must have its type.
+		if h["post type"] >= len(record) {
+			if !isBlankRecord(record) {
+				c.skip(fmt.Sprintf("%d fields, too few for the post type", len(record)))
+			}
+			continue
+		}
+
+		post := model.PostData{
+			Date:          strings.TrimSpace(h.get(record, "date")),
+			PostedAt:      ParsePostDate(h.get(record, "date")),
+			SocialAccount: strings.TrimSpace(h.get(record, "social account")),
+			SocialNetwork: strings.TrimSpace(h.get(record, "social network")),
+			PostLink:      strings.TrimSpace(h.get(record, "post link")),
+			PostText:      strings.TrimSpace(h.get(record, "post text")),
+			PostType:      strings.TrimSpace(h.get(record, "post type")),
+		}
+		post.Reach = c.metric(h, record, "reach")
+		post.ReachRate = c.float(h, record, "reach rate")
+		post.Reactions = c.metric(h, record, "reactions")
+		post.Comments = c.metric(h, record, "comments")
+		post.Shares = c.metric(h, record, "shares")
+		post.EngagementRate = c.float(h, record, "engagement rate")
+		post.LinkClicks = c.metric(h, record, "link clicks")
+		post.ClickThroughRate = c.float(h, record, "click through rate")
+		posts = append(posts, post)
+	}
+
+	return posts, c.warnings, nil
+}
+
+// postDateLayouts are the formats of the post dates in Publer exports,
+// which give the time in the workspace's timezone without an offset, and of
+// re-saved or API exports.
+var postDateLayouts = []string{
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+	"Jan 2, 2006 15:04",
+	"Jan 2, 2006 3:04 PM",
+	"2 Jan 2006 15:04",
+	"02.01.2006 15:04",
+}
+
+// ParsePostDate returns the time of a post date, or the zero time if s is
+// empty or has an unknown format. A date with an offset keeps it, so that
+// the wall-clock hour is the one the post was published at.
+func ParsePostDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+	for _, layout := range postDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// ReadHashtagAnalysisFile reads the hashtag analysis export in filename.
+// See ReadHashtagAnalysis.
+func ReadHashtagAnalysisFile(filename string, opts Options) ([]model.HashtagData, []Warning, error) {
+	file, err := openCSV(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	return readHashtagAnalysis(file, filename, opts)
+}
+
+// ReadHashtagAnalysis reads the hashtags of a hashtag analysis export from
+// r, which may be gzip-compressed. The name identifies the export in errors
+// and warnings. The warnings list the cells that were read as zero.
+func ReadHashtagAnalysis(r io.Reader, name string, opts Options) ([]model.HashtagData, []Warning, error) {
+	rc, err := decompress(r, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rc.Close()
+
+	return readHashtagAnalysis(rc, name, opts)
+}
+
+func readHashtagAnalysis(r io.Reader, filename string, opts Options) ([]model.HashtagData, []Warning, error) {
+	reader := newCSVReader(r, opts)
+	c := newCells(reader, filename)
+
+	h, width, err := readHeader(reader, "Hashtag", opts.HashtagColumns)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	var hashtags []model.HashtagData
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if err := c.readErr(err, opts.StrictRows); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if opts.StrictRows {
+			if err := c.checkWidth(record, width); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		hashtag := model.HashtagData{
+			Hashtag: strings.TrimSpace(h.get(record, "hashtag")),
+		}
+		if hashtag.Hashtag == "" {
+			if !isBlankRecord(record) {
+				c.skip("no hashtag")
+			}
+			continue
+		}
+		hashtag.Score = c.float(h, record, "score")
+		hashtag.Reach = c.metric(h, record, "reach")
+		hashtag.Reactions = c.metric(h, record, "reactions")
+		hashtag.Comments = c.metric(h, record, "comments")
+		hashtag.Shares = c.metric(h, record, "shares")
+		hashtag.VideoViews = c.metric(h, record, "video views")
+
+		hashtags = append(hashtags, hashtag)
+	}
+
+	return hashtags, c.warnings, nil
+}
@@ -0,0 +1,693 @@
+package parser
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/christophberger/publer-analytics-report/model"
+)
+
+// sampleFile returns the path of the export of the given type in one of the
+// sample directories of the repository's testdata.
+func sampleFile(t *testing.T, dir, kind string) string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join("..", "testdata", dir, "*"+kind+"*.csv"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("no single %s export in testdata/%s: %v %v", kind, dir, files, err)
+	}
+	return files[0]
+}
+
+func TestReadSamples(t *testing.T) {
+	tests := []struct {
+		dir            string
+		followers      int
+		reach          int
+		engagementRate float64
+		countries      int
+		topCountry     string
+		topUsers       int
+		posts          int
+		firstPostDate  string
+		firstPostReach int
+		hashtags       int
+		firstHashtag   string
+		firstScore     float64
+	}{
+		{"2025-06", 4707, 157, 25.48, 10, "Switzerland", 445, 10, "2025-06-26 10:11", 44, 24, "#agenticai", 13.64},
+		{"2025-07", 4750, 507, 20.71, 10, "Switzerland", 446, 23, "2025-07-31 10:45", 34, 37, "#agenticai", 4.76},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			overview, warnings, err := ReadOverviewFile(sampleFile(t, tt.dir, "Overview"), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(warnings) > 0 {
+				t.Errorf("overview warnings: %v", warnings)
+			}
+			if overview.WorkspaceName != "ACME Inc (Workspace)" {
+				t.Errorf("workspace = %q", overview.WorkspaceName)
+			}
+			if overview.Followers != tt.followers || overview.Reach != tt.reach || overview.EngagementRate != tt.engagementRate {
+				t.Errorf("followers, reach, engagement rate = %d, %d, %v, want %d, %d, %v",
+					overview.Followers, overview.Reach, overview.EngagementRate, tt.followers, tt.reach, tt.engagementRate)
+			}
+			if len(overview.TopCountries) != tt.countries {
+				t.Fatalf("%d countries, want %d", len(overview.TopCountries), tt.countries)
+			}
+			if c := overview.TopCountries[0]; c.Country != tt.topCountry || c.Users != tt.topUsers {
+				t.Errorf("top country = %s with %d users, want %s with %d", c.Country, c.Users, tt.topCountry, tt.topUsers)
+			}
+			total := 0.0
+			for _, c := range overview.TopCountries {
+				total += c.Percentage
+			}
+			if total < 99.99 || total > 100.01 {
+				t.Errorf("country percentages add up to %v, want 100", total)
+			}
+
+			posts, warnings, err := ReadPostInsightsFile(sampleFile(t, tt.dir, "Post Insights"), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(warnings) > 0 {
+				t.Errorf("post warnings: %v", warnings)
+			}
+			if len(posts) != tt.posts {
+				t.Fatalf("%d posts, want %d", len(posts), tt.posts)
+			}
+			if p := posts[0]; p.Date != tt.firstPostDate || p.Reach != tt.firstPostReach || p.PostType != "Link" || p.SocialNetwork != "Linkedin" {
+				t.Errorf("first post = %+v", p)
+			}
+			if posts[0].PostedAt.IsZero() {
+				t.Errorf("date %q of the first post not parsed", posts[0].Date)
+			}
+
+			hashtags, warnings, err := ReadHashtagAnalysisFile(sampleFile(t, tt.dir, "Hashtag Analysis"), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(warnings) > 0 {
+				t.Errorf("hashtag warnings: %v", warnings)
+			}
+			if len(hashtags) != tt.hashtags {
+				t.Fatalf("%d hashtags, want %d", len(hashtags), tt.hashtags)
+			}
+			if h := hashtags[0]; h.Hashtag != tt.firstHashtag || h.Score != tt.firstScore {
+				t.Errorf("first hashtag = %s with score %v, want %s with %v", h.Hashtag, h.Score, tt.firstHashtag, tt.firstScore)
+			}
+		})
+	}
+}
+
+func TestReadMissingFile(t *testing.T) {
+	if _, _, err := ReadOverviewFile(filepath.Join("testdata", "missing.csv"), Options{}); err == nil {
+		t.Error("reading a missing file succeeded")
+	}
+}
+
+func TestInvalidCellsWarn(t *testing.T) {
+	const header = "Date,Social account,Social network,Post link,Post text,Post type,Reach,Reach rate (%),Reactions,Comments,Shares,Engagement rate (%),Link clicks,Click through rate (%)\n"
+	tests := []struct {
+		name  string
+		row   string
+		cells []string
+	}{
+		{"valid", "2025-07-01 10:00,Acme,Linkedin,https://example.com/1,Hello,Status,120,2.5,9,1,0,7.5,-,-", nil},
+		{"text", "2025-07-01 10:00,Acme,Linkedin,https://example.com/1,Hello,Status,N/A,2.5,9,1,0,7.5,-,-", []string{"reach=N/A"}},
+		{"not a number", "2025-07-01 10:00,Acme,Linkedin,https://example.com/1,Hello,Status,NaN,nan,9,1,0,7.5,-,-", []string{"reach=NaN", "reach rate=nan"}},
+		{"infinite", "2025-07-01 10:00,Acme,Linkedin,https://example.com/1,Hello,Status,120,2.5,Inf,-Inf,+infinity,7.5,-,-", []string{"reactions=Inf", "comments=-Inf", "shares=+infinity"}},
+		{"out of range", "2025-07-01 10:00,Acme,Linkedin,https://example.com/1,Hello,Status,1e30,1e400,9,1,0,7.5,-,-", []string{"reach=1e30", "reach rate=1e400"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts, warnings, err := ReadPostInsights(strings.NewReader(header+tt.row+"\n"), "posts.csv", Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(posts) != 1 {
+				t.Fatalf("%d posts, want 1", len(posts))
+			}
+			var cells []string
+			for _, w := range warnings {
+				if w.File != "posts.csv" || w.Line != 2 {
+					t.Errorf("warning at %s:%d, want posts.csv:2", w.File, w.Line)
+				}
+				cells = append(cells, w.Column+"="+w.Value)
+			}
+			if !slices.Equal(cells, tt.cells) {
+				t.Errorf("warnings for %v, want %v", cells, tt.cells)
+			}
+			p := posts[0]
+			if p.Reach < 0 || p.Reactions < 0 || p.Comments < 0 || p.Shares < 0 || math.IsNaN(p.ReachRate) {
+				t.Errorf("invalid cells not read as zero: %+v", p)
+			}
+		})
+	}
+}
+
+func TestInvalidTotalsFail(t *testing.T) {
+	for _, followers := range []string{"NaN", "Inf", "-Inf", "N/A"} {
+		csv := "Workspace Name,Followers,Reach,Reach Rate,Engagements,Engagement Rate\nAcme," + followers + ",507,3.59,105,20.71%\n"
+		if _, _, err := ReadOverview(strings.NewReader(csv), "overview.csv", Options{}); err == nil || !strings.Contains(err.Error(), "followers") {
+			t.Errorf("followers %s: error = %v, want one about followers", followers, err)
+		}
+	}
+}
+
+func TestSniffDelimiter(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want rune
+	}{
+		{"comma", "# Start Date: 1 Jul 2025\n\nHashtag,Posts,Score\n#go,1,4.5\n", ','},
+		{"semicolon", "# Start Date: 1 Jul 2025\n\nHashtag;Posts;Score\n#go;1;4,5\n", ';'},
+		{"tab", "Hashtag\tPosts\tScore\n#go\t1\t4.5\n", '\t'},
+		{"quoted commas", "Hashtag;Post text;Score\n#go;\"a, b, c, d\";4,5\n", ';'},
+		{"comments only", "# Start Date: 1 Jul 2025; End Date: 31 Jul 2025\n", ','},
+		{"empty", "", ','},
+	}
+	for _, tt := range tests {
+		if got := sniffDelimiter([]byte(tt.head)); got != tt.want {
+			t.Errorf("%s: sniffDelimiter = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNumberFormats(t *testing.T) {
+	tests := []struct {
+		s      string
+		f      numberFormat
+		metric int
+		float  float64
+		err    bool
+	}{
+		{"1,234", pointDecimal, 1234, 1234, false},
+		{"1,234,567.5", pointDecimal, 1234568, 1234567.5, false},
+		{"20.71%", pointDecimal, 21, 20.71, false},
+		{"-1,234", pointDecimal, -1234, -1234, false},
+		{"3,5", pointDecimal, 0, 0, true},
+		{"2,75%", pointDecimal, 0, 0, true},
+		{"1,2345", pointDecimal, 0, 0, true},
+		{",123", pointDecimal, 0, 0, true},
+		{"1.234,5", pointDecimal, 0, 0, true},
+		{"1.234", commaDecimal, 1234, 1234, false},
+		{"1.234.567,5", commaDecimal, 1234568, 1234567.5, false},
+		{"3,5", commaDecimal, 4, 3.5, false},
+		{"2,75%", commaDecimal, 3, 2.75, false},
+		{"12 480", commaDecimal, 12480, 12480, false},
+		{"12\u00a0480", commaDecimal, 12480, 12480, false},
+		{"12\u202f480", commaDecimal, 12480, 12480, false},
+		{"1.23", commaDecimal, 0, 0, true},
+		{"1,234.5", commaDecimal, 0, 0, true},
+		{"-", commaDecimal, 0, 0, false},
+		{"", pointDecimal, 0, 0, false},
+	}
+	for _, tt := range tests {
+		metric, err := parseMetric(tt.s, tt.f)
+		if (err != nil) != tt.err || metric != tt.metric {
+			t.Errorf("parseMetric(%q, %q) = %d, %v, want %d, error %v", tt.s, tt.f.decimal, metric, err, tt.metric, tt.err)
+		}
+		float, err := parseFloatLoose(tt.s, tt.f)
+		if (err != nil) != tt.err || float != tt.float {
+			t.Errorf("parseFloatLoose(%q, %q) = %v, %v, want %v, error %v", tt.s, tt.f.decimal, float, err, tt.float, tt.err)
+		}
+	}
+}
+
+func TestSemicolonExports(t *testing.T) {
+	for _, delim := range []rune{0, ';'} {
+		opts := Options{Delimiter: delim}
+		overview, warnings, err := ReadOverviewFile("testdata/semicolon-overview.csv", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(warnings) > 0 {
+			t.Errorf("overview warnings: %v", warnings)
+		}
+		if overview.WorkspaceName != "ACME GmbH (Workspace)" || overview.Followers != 12480 || overview.Reach != 3215 ||
+			overview.ReachRate != 25.76 || overview.Engagements != 1043 || overview.EngagementRate != 32.44 {
+			t.Errorf("overview = %+v", overview)
+		}
+		if len(overview.TopCountries) != 2 || overview.TopCountries[0].Users != 1204 {
+			t.Errorf("countries = %+v", overview.TopCountries)
+		}
+
+		posts, warnings, err := ReadPostInsightsFile("testdata/semicolon-posts.csv", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(warnings) > 0 {
+			t.Errorf("post warnings: %v", warnings)
+		}
+		if len(posts) != 2 {
+			t.Fatalf("%d posts, want 2", len(posts))
+		}
+		if p := posts[0]; p.PostText != "Grüße; und bis bald" || p.Reach != 1234 || p.ReachRate != 2.65 || p.Reactions != 1200 || p.EngagementRate != 5.88 {
+			t.Errorf("first post = %+v", p)
+		}
+		if p := posts[1]; p.EngagementRate != 2.75 || p.LinkClicks != 4 || p.ClickThroughRate != 1.5 {
+			t.Errorf("second post = %+v", p)
+		}
+	}
+}
+
+func TestDecimalCommaInCommaExport(t *testing.T) {
+	csv := "Hashtag,Score,Reach\n#go,\"3,5\",\"1,234\"\n"
+	hashtags, warnings, err := ReadHashtagAnalysis(strings.NewReader(csv), "hashtags.csv", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashtags) != 1 || hashtags[0].Score != 0 || hashtags[0].Reach != 1234 {
+		t.Errorf("hashtags = %+v", hashtags)
+	}
+	if len(warnings) != 1 || warnings[0].Column != "score" || warnings[0].Value != "3,5" {
+		t.Errorf("warnings = %v, want one for the score 3,5", warnings)
+	}
+}
+
+func TestParseMetric(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+		err  bool
+	}{
+		{"1234", 1234, false},
+		{"1,234", 1234, false},
+		{"1,234,567", 1234567, false},
+		{" 1,234 ", 1234, false},
+		{"12%", 12, false},
+		{"-12", -12, false},
+		{"-1,234", -1234, false},
+		{"-1,234,567", -1234567, false},
+		{"+5", 5, false},
+		{"-", 0, false},
+		{"", 0, false},
+		{"  ", 0, false},
+		{"1.2K", 1200, false},
+		{"3k", 3000, false},
+		{"1.5M", 1500000, false},
+		{"2B", 2000000000, false},
+		{"K", 0, true},
+		{"N/A", 0, true},
+		{"12abc", 0, true},
+		{"1,2,3", 0, true},
+		{"1.2.3", 0, true},
+		{"--5", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseMetric(tt.s, pointDecimal)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("parseMetric(%q) = %d, %v, want %d, error %v", tt.s, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestThousandsSeparatorsInRows(t *testing.T) {
+	csv := "Hashtag,Score,Reach\n#go,12,\"1,234,567\"\n#rust,-3,\"-1,234\"\n"
+	hashtags, _, err := ReadHashtagAnalysis(strings.NewReader(csv), "hashtags.csv", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashtags) != 2 || hashtags[0].Reach != 1234567 || hashtags[1].Score != -3 || hashtags[1].Reach != -1234 {
+		t.Errorf("hashtags = %+v", hashtags)
+	}
+}
+
+func TestParseMetricSuffixes(t *testing.T) {
+	tests := []struct {
+		s    string
+		f    numberFormat
+		want int
+		err  bool
+	}{
+		{"1.2K", pointDecimal, 1200, false},
+		{"1.25K", pointDecimal, 1250, false},
+		{"1.25k", pointDecimal, 1250, false},
+		{"3.4M", pointDecimal, 3400000, false},
+		{"3.4m", pointDecimal, 3400000, false},
+		{"1.1B", pointDecimal, 1100000000, false},
+		{"-1.5K", pointDecimal, -1500, false},
+		{" 2K ", pointDecimal, 2000, false},
+		{"1,25K", commaDecimal, 1250, false},
+		{"1.6", pointDecimal, 2, false},
+		{"1.4", pointDecimal, 1, false},
+		{"K", pointDecimal, 0, true},
+		{"m", pointDecimal, 0, true},
+		{"-K", pointDecimal, 0, true},
+		{"1.2KB", pointDecimal, 0, true},
+		{"1.2X", pointDecimal, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseMetric(tt.s, tt.f)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("parseMetric(%q, %q) = %d, %v, want %d, error %v", tt.s, tt.f.decimal, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestShortPostRows(t *testing.T) {
+	header := "Date,Social account,Social network,Post link,Post text,Post type,Reach,Reach rate (%),Reactions,Comments,Shares,Engagement rate (%),Link clicks,Click through rate (%),Action\n"
+	tests := []struct {
+		name      string
+		row       string
+		reach     int
+		reactions int
+	}{
+		{"8 fields", "2025-07-31 10:45,ACME,Linkedin,https://example.com/1,Hello,Link,34,2.65\n", 34, 0},
+		{"9 fields", "2025-07-31 10:45,ACME,Linkedin,https://example.com/1,Hello,Link,34,2.65,7\n", 34, 7},
+	}
+	for _, tt := range tests {
+		posts, warnings, err := ReadPostInsights(strings.NewReader(header+tt.row), "posts.csv", Options{})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(warnings) > 0 {
+			t.Errorf("%s: warnings %v", tt.name, warnings)
+		}
+		if len(posts) != 1 {
+			t.Errorf("%s: %d posts, want 1", tt.name, len(posts))
+			continue
+		}
+		if p := posts[0]; p.PostType != "Link" || p.Reach != tt.reach || p.ReachRate != 2.65 || p.Reactions != tt.reactions || p.Comments != 0 || p.LinkClicks != 0 {
+			t.Errorf("%s: post %+v, want reach %d and %d reactions", tt.name, p, tt.reach, tt.reactions)
+		}
+	}
+
+	// With --strict-csv, a short row is an error instead.
+	if _, _, err := ReadPostInsights(strings.NewReader(header+tests[0].row), "posts.csv", Options{StrictRows: true}); err == nil {
+		t.Error("a short row with StrictRows succeeded, want an error")
+	}
+}
+
+func TestShortHashtagRows(t *testing.T) {
+	header := "Hashtag,Top performing posts,Posts,Recent posts,Score,Reach,Reactions,Comments,Shares,Video views\n"
+	tests := []struct {
+		name      string
+		row       string
+		reach     int
+		reactions int
+	}{
+		{"6 columns", "#go,https://example.com/1,1,https://example.com/1,4.76,120\n", 120, 0},
+		{"7 columns", "#go,https://example.com/1,1,https://example.com/1,4.76,120,5\n", 120, 5},
+	}
+	for _, tt := range tests {
+		hashtags, warnings, err := ReadHashtagAnalysis(strings.NewReader(header+tt.row), "hashtags.csv", Options{})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(warnings) > 0 {
+			t.Errorf("%s: warnings %v", tt.name, warnings)
+		}
+		want := model.HashtagData{Hashtag: "#go", Score: 4.76, Reach: tt.reach, Reactions: tt.reactions}
+		if len(hashtags) != 1 || hashtags[0] != want {
+			t.Errorf("%s: hashtags %+v, want %+v", tt.name, hashtags, want)
+		}
+	}
+}
+
+func TestOverviewDataRowAfterBlankLines(t *testing.T) {
+	header := "Workspace Name,Number of Social Accounts,Followers,Reach,Reach Rate,Video Views,Engagements,Engagement Rate\n"
+	row := "ACME Inc (Workspace),4,4750,507,3.59,0,105,20.71%\n"
+	tests := []struct {
+		name string
+		csv  string
+		err  string
+	}{
+		{"no blank line", header + row, ""},
+		{"empty line", header + "\n" + row, ""},
+		{"line of empty fields", header + ",,,,,,,\n" + row, ""},
+		{"several blank lines", header + "\n,,,,,,,\n\n" + row, ""},
+		{"no data row", header + "\n,,,,,,,\n", `overview.csv: no data row after the "Workspace Name" header`},
+		{"non-numeric data row", header + "\nACME Inc (Workspace),4,many,507,3.59,0,105,20.71%\n", "overview.csv: line 3: "},
+	}
+	for _, tt := range tests {
+		overview, _, err := ReadOverview(strings.NewReader(tt.csv), "overview.csv", Options{})
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, want one containing %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if overview.WorkspaceName != "ACME Inc (Workspace)" || overview.Followers != 4750 || overview.Reach != 507 || overview.EngagementRate != 20.71 {
+			t.Errorf("%s: overview %+v", tt.name, overview)
+		}
+	}
+}
+
+func TestGzipExports(t *testing.T) {
+	// The fixtures are the July samples, gzipped.
+	tests := []struct {
+		kind string
+		gz   string
+		read func(string, Options) (any, error)
+	}{
+		{"Overview", "testdata/gzip-overview.csv.gz", func(f string, o Options) (any, error) {
+			data, _, err := ReadOverviewFile(f, o)
+			return data, err
+		}},
+		{"Post Insights", "testdata/gzip-post-insights.csv.gz", func(f string, o Options) (any, error) {
+			data, _, err := ReadPostInsightsFile(f, o)
+			return data, err
+		}},
+		{"Hashtag Analysis", "testdata/gzip-hashtag-analysis.csv.gz", func(f string, o Options) (any, error) {
+			data, _, err := ReadHashtagAnalysisFile(f, o)
+			return data, err
+		}},
+	}
+	for _, tt := range tests {
+		want, err := tt.read(sampleFile(t, "2025-07", tt.kind), Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// A gzipped file is recognized by its magic bytes, with or
+		// without the .gz extension.
+		data, err := os.ReadFile(tt.gz)
+		if err != nil {
+			t.Fatal(err)
+		}
+		renamed := filepath.Join(t.TempDir(), "export.csv")
+		if err := os.WriteFile(renamed, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range []string{tt.gz, renamed} {
+			got, err := tt.read(f, Options{})
+			if err != nil {
+				t.Errorf("%s: %v", f, err)
+				continue
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: read %+v, want %+v", f, got, want)
+			}
+		}
+	}
+
+	if _, _, err := ReadOverview(strings.NewReader("\x1f\x8bnot gzip"), "broken.csv.gz", Options{}); err == nil || !strings.Contains(err.Error(), "broken.csv.gz") {
+		t.Errorf("broken gzip data: error %v, want one naming the file", err)
+	}
+}
+
+func TestByteOrderMark(t *testing.T) {
+	// The fixture is the July sample as Excel saves it: with a BOM right
+	// before the header and CRLF line endings.
+	want, _, err := ReadOverviewFile(sampleFile(t, "2025-07", "Overview"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := ReadOverviewFile("testdata/bom-overview.csv", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.WorkspaceName != "ACME Inc (Workspace)" || !reflect.DeepEqual(got, want) {
+		t.Errorf("overview with a BOM = %+v, want %+v", got, want)
+	}
+
+	const bom = "\ufeff"
+	posts, _, err := ReadPostInsights(strings.NewReader(bom+"Date,Post type,Reach\n2025-07-31 10:45,Link,34\n"), "posts.csv", Options{})
+	if err != nil || len(posts) != 1 || posts[0].Date != "2025-07-31 10:45" || posts[0].Reach != 34 {
+		t.Errorf("posts with a BOM = %+v, %v", posts, err)
+	}
+	hashtags, _, err := ReadHashtagAnalysis(strings.NewReader(bom+"Hashtag,Score,Reach\n#go,4.76,120\n"), "hashtags.csv", Options{})
+	if err != nil || len(hashtags) != 1 || hashtags[0].Hashtag != "#go" || hashtags[0].Reach != 120 {
+		t.Errorf("hashtags with a BOM = %+v, %v", hashtags, err)
+	}
+}
+
+func TestPostRates(t *testing.T) {
+	header := "Date,Post link,Post type,Reactions,Engagement rate (%),Link clicks,Click through rate (%)\n"
+	tests := []struct {
+		name     string
+		row      string
+		rate     float64
+		ctr      float64
+		warnings int
+	}{
+		{"numbers", "2025-07-31 10:45,https://example.com/1,Link,1,5.88,2,1.5\n", 5.88, 1.5, 0},
+		{"percent signs", "2025-07-31 10:45,https://example.com/1,Link,1,5.88%,2,1.5%\n", 5.88, 1.5, 0},
+		{"dashes", "2025-07-31 10:45,https://example.com/1,Link,1,-,-,-\n", 0, 0, 0},
+		{"blank cells", "2025-07-31 10:45,https://example.com/1,Link,1,,,\n", 0, 0, 0},
+		{"not a number", "2025-07-31 10:45,https://example.com/1,Link,1,n/a,2,1.5\n", 0, 1.5, 1},
+	}
+	for _, tt := range tests {
+		posts, warnings, err := ReadPostInsights(strings.NewReader(header+tt.row), "posts.csv", Options{})
+		if err != nil || len(posts) != 1 {
+			t.Errorf("%s: %d posts, %v", tt.name, len(posts), err)
+			continue
+		}
+		if p := posts[0]; p.EngagementRate != tt.rate || p.ClickThroughRate != tt.ctr || len(warnings) != tt.warnings {
+			t.Errorf("%s: engagement rate %v, CTR %v, %d warnings, want %v, %v, %d", tt.name, p.EngagementRate, p.ClickThroughRate, len(warnings), tt.rate, tt.ctr, tt.warnings)
+		}
+	}
+}
+
+func TestReorderedColumns(t *testing.T) {
+	// The reordered samples hold the July data with the columns of each
+	// table in a different order.
+	tests := []struct {
+		kind string
+		read func(string) (any, []Warning, error)
+	}{
+		{"Overview", func(f string) (any, []Warning, error) { return ReadOverviewFile(f, Options{}) }},
+		{"Post Insights", func(f string) (any, []Warning, error) { return ReadPostInsightsFile(f, Options{}) }},
+		{"Hashtag Analysis", func(f string) (any, []Warning, error) { return ReadHashtagAnalysisFile(f, Options{}) }},
+	}
+	for _, tt := range tests {
+		want, _, err := tt.read(sampleFile(t, "2025-07", tt.kind))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, warnings, err := tt.read(sampleFile(t, "2025-07-reordered", tt.kind))
+		if err != nil {
+			t.Errorf("%s: %v", tt.kind, err)
+			continue
+		}
+		if len(warnings) > 0 {
+			t.Errorf("%s: warnings %v", tt.kind, warnings)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: reordered columns read %+v, want %+v", tt.kind, got, want)
+		}
+	}
+
+	// Unknown columns are ignored, and missing ones read as zero or empty.
+	csv := "Mood,Reactions,Post type,Shares\nhappy,7,Photo,2\n"
+	posts, warnings, err := ReadPostInsights(strings.NewReader(csv), "posts.csv", Options{})
+	want := []model.PostData{{PostType: "Photo", Reactions: 7, Shares: 2}}
+	if err != nil || len(warnings) > 0 || !reflect.DeepEqual(posts, want) {
+		t.Errorf("posts with unknown and missing columns = %+v, %v, %v, want %+v", posts, warnings, err, want)
+	}
+}
+
+func TestOverviewReadErrorBelowTotals(t *testing.T) {
+	// The reader tolerates stray quotes, so a read error is what can break
+	// off the tables below the totals, as in a truncated download.
+	rows := "Workspace Name,Followers,Reach\nAcme,100,50\n\nTop Countries,Users\nSwitzerland,40\n"
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(rows + strings.Repeat("Germany,30\n", 2000)))
+	zw.Close()
+	truncated := gz.Bytes()[:gz.Len()-20]
+
+	tests := []struct {
+		name string
+		r    func() io.Reader
+		err  string
+	}{
+		{"failing reader", func() io.Reader {
+			return io.MultiReader(strings.NewReader(rows), iotest.ErrReader(errors.New("connection reset")))
+		}, "overview.csv: connection reset"},
+		{"truncated gzip", func() io.Reader { return bytes.NewReader(truncated) }, "overview.csv: unexpected EOF"},
+	}
+	for _, tt := range tests {
+		for _, strict := range []bool{false, true} {
+			_, _, err := ReadOverview(tt.r(), "overview.csv", Options{StrictRows: strict})
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s, StrictRows %v: error %v, want one containing %q", tt.name, strict, err, tt.err)
+			}
+		}
+	}
+}
+
+func TestStrictMonthRows(t *testing.T) {
+	const header = "Month,Workspace Name,Followers,Reach\nJul 2025,Acme,100,50\n"
+	tests := []struct {
+		name string
+		row  string
+		err  string
+	}{
+		{"wide month row", "Jun 2025,Acme,90,40,extra\n", "overview.csv: line 3: 5 fields, expected 4"},
+		{"short month row", "Jun 2025,Acme,90\n", "overview.csv: line 3: 3 fields, expected 4"},
+	}
+	for _, tt := range tests {
+		overview, _, err := ReadOverview(strings.NewReader(header+tt.row), "overview.csv", Options{})
+		if err != nil || len(overview.Months) != 2 {
+			t.Errorf("%s: %+v, %v, want two months", tt.name, overview, err)
+		}
+		_, _, err = ReadOverview(strings.NewReader(header+tt.row), "overview.csv", Options{StrictRows: true})
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s with StrictRows: error %v, want one containing %q", tt.name, err, tt.err)
+		}
+	}
+
+	// A month row with a total that is not a number is an error, as it is
+	// in the first row.
+	_, _, err := ReadOverview(strings.NewReader(header+"Jun 2025,Acme,many,40\n"), "overview.csv", Options{})
+	if err == nil || !strings.Contains(err.Error(), "overview.csv: line 3: ") {
+		t.Errorf("invalid month total: error %v, want one naming line 3", err)
+	}
+}
+
+func TestParseFloatLoose(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+		err  bool
+	}{
+		{"3.59%", 3.59, false},
+		{"1,234.5", 1234.5, false},
+		{"-0.5", -0.5, false},
+		{"-", 0, false},
+		{"", 0, false},
+		{"1.2K", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseFloatLoose(tt.s, pointDecimal)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("parseFloatLoose(%q) = %v, %v, want %v, error %v", tt.s, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestReadOverviewThousands(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "overview.csv")
+	csv := "Workspace Name,Period,Followers,Reach,Reach Rate,Impressions,Engagements,Engagement Rate\nAcme,Jul 2025,\"4,750\",\"1,234,567\",3.59%,0,\"-1,234\",20.71%\n"
+	if err := os.WriteFile(filename, []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, _, err := ReadOverviewFile(filename, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Followers != 4750 || data.Reach != 1234567 || data.Engagements != -1234 || data.ReachRate != 3.59 || data.EngagementRate != 20.71 {
+		t.Errorf("overview = %+v", data)
+	}
+}
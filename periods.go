@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/christophberger/publer-analytics-report/report"
+)
+
+func runPeriods(args []string) error {
+	fs := flag.NewFlagSet("periods", flag.ExitOnError)
+	dbPath := fs.String("db", "", "path to the SQLite database (default $PUBLER_DB or analytics.db)")
+	configFile := fs.String("config", report.DefaultConfigFile, "path to the configuration file with the database settings (default searches ./config.yaml, then the user config directory)")
+	workspace := fs.String("workspace", "", "only list the periods of this workspace")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s periods [flags]\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	return report.ListPeriods(context.Background(), *dbPath, *configFile, *workspace)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/christophberger/publer-analytics-report/report"
+)
+
+func runReportRange(args []string) error {
+	fs := flag.NewFlagSet("report-range", flag.ExitOnError)
+	since := fs.String("since", "", "first period to include, as YYYY-MM")
+	until := fs.String("until", "", "last period to include, as YYYY-MM")
+	workspace := fs.String("workspace", "", "workspace to report on (default the only one in the database)")
+	dbPath := fs.String("db", "", "path to the SQLite database (default $PUBLER_DB or analytics.db)")
+	configFile := fs.String("config", report.DefaultConfigFile, "path to the configuration file with the database settings (default searches ./config.yaml, then the user config directory)")
+	var output string
+	fs.StringVar(&output, "o", "", "output file or directory for the report (shorthand)")
+	fs.StringVar(&output, "output", "", "output file or directory for the report")
+	format := fs.String("format", "md", "report format: md or json")
+	top := fs.Int("top", 5, "number of top posts and hashtags to show (0 or less shows all)")
+	decimals := fs.Int("decimals", report.DefaultDecimals, "number of decimal places of percentages and averages in the report")
+	hashtagRankBy := fs.String("hashtag-rank-by", report.DefaultHashtagRankBy, "metric to rank the top hashtags by: score, reach, or engagement")
+	rankBy := fs.String("rank-by", report.DefaultRankBy, "metric to rank the top posts by: reactions, engagements, reach, or clicks")
+	postTypes := fs.String("post-types", report.DefaultPostTypes, `comma-separated post types to rank as top posts, or "all"`)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s report-range --since YYYY-MM --until YYYY-MM [flags]\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	by, err := report.ParseRankBy(*rankBy)
+	if err != nil {
+		return usageError{fmt.Errorf("error parsing ranking: %w", err)}
+	}
+	hashtagBy, err := report.ParseHashtagRankBy(*hashtagRankBy)
+	if err != nil {
+		return usageError{fmt.Errorf("error parsing hashtag ranking: %w", err)}
+	}
+
+	return report.RunRange(context.Background(), report.RangeOptions{
+		Since:      *since,
+		Until:      *until,
+		Workspace:  *workspace,
+		DBPath:     *dbPath,
+		ConfigFile: *configFile,
+		Output:     output,
+		Format:     *format,
+		Report:     report.ReportOptions{PostTypes: report.ParsePostTypes(*postTypes), Top: *top, RankBy: by, HashtagRankBy: hashtagBy, Decimals: *decimals},
+	})
+}
@@ -0,0 +1,83 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// masterReportFilename is the name of the file that --append collects the
+// monthly Markdown reports of a workspace in.
+func masterReportFilename(workspaceName string) string {
+	return cleanWorkspaceName(workspaceName) + ".md"
+}
+
+// appendReport inserts report as the "## <month>" section of the master
+// report in filename, replacing the section if the month is already there
+// and creating the file on the first run.
+func appendReport(report, filename, workspace, month string) error {
+	doc, err := os.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(doc) == 0 {
+		doc = []byte(fmt.Sprintf("# %s KPIs\n", cleanWorkspaceName(workspace)))
+	}
+
+	heading := "## " + month
+	section := heading + "\n\n" + strings.TrimSpace(demoteHeadings(report)) + "\n"
+	return writeReport(upsertSection(string(doc), heading, section), filename)
+}
+
+// upsertSection replaces the level-two section that starts with heading, up
+// to the next level-two heading, or appends section if doc has none.
+func upsertSection(doc, heading, section string) string {
+	lines := strings.SplitAfter(doc, "\n")
+	start, end := -1, len(lines)
+	for i, line := range lines {
+		l := strings.TrimRight(line, "\r\n")
+		if start < 0 {
+			if strings.TrimSpace(l) == heading {
+				start = i
+			}
+			continue
+		}
+		if strings.HasPrefix(l, "## ") {
+			end = i
+			break
+		}
+	}
+
+	if start < 0 {
+		return strings.TrimRight(doc, "\n") + "\n\n" + section
+	}
+	if end < len(lines) {
+		section += "\n"
+	}
+	return strings.Join(lines[:start], "") + section + strings.Join(lines[end:], "")
+}
+
+// demoteHeadings drops the report's leading level-one title, which the
+// month heading replaces, and moves all other headings down to level three
+// or deeper, so that they stay inside the month's section. Fenced code
+// blocks are left alone.
+func demoteHeadings(report string) string {
+	lines := strings.Split(report, "\n")
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "# ") {
+		lines = lines[1:]
+	}
+
+	inFence := false
+	for i, line := range lines {
+		if strings.HasPrefix(line, "```") {
+			inFence = !inFence
+			continue
+		}
+		level := len(line) - len(strings.TrimLeft(line, "#"))
+		if inFence || level == 0 || level >= len(line) || line[level] != ' ' {
+			continue
+		}
+		lines[i] = strings.Repeat("#", max(level+1, 3)) + line[level:]
+	}
+	return strings.Join(lines, "\n")
+}
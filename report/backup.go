@@ -0,0 +1,109 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/christophberger/publer-analytics-report/store"
+)
+
+// ExportDB writes every table of the database at dbPath to out as a JSON
+// document, or to stdout if out is empty or "-". It does not modify the
+// database, which is opened with the settings of configFile.
+func ExportDB(ctx context.Context, dbPath, configFile, out string) error {
+	path, err := DefaultDBPath(dbPath)
+	if err != nil {
+		return fmt.Errorf("error resolving database path: %w", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("database %s not found", path)
+	}
+
+	dbOpts, err := dbOptions(configFile)
+	if err != nil {
+		return err
+	}
+
+	db, err := store.Open(path, dbOpts)
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer db.Close()
+
+	dump, err := store.Export(ctx, db)
+	if err != nil {
+		return fmt.Errorf("error exporting database: %w", err)
+	}
+	b, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding database export: %w", err)
+	}
+	b = append(b, '\n')
+
+	if out == "" || out == "-" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	if err := writeReport(string(b), out); err != nil {
+		return fmt.Errorf("error writing database export: %w", err)
+	}
+	rows := 0
+	for _, t := range dump.Tables {
+		rows += len(t.Rows)
+	}
+	fmt.Printf("Exported %d rows of %s at schema version %d to %s\n", rows, path, dump.SchemaVersion, out)
+	return nil
+}
+
+// ImportDB creates the database at dbPath from the JSON document in, as
+// written by ExportDB, and migrates it to the latest schema version. The
+// database must not exist yet; it is opened with the settings of
+// configFile.
+func ImportDB(ctx context.Context, dbPath, configFile, in string) error {
+	path, err := DefaultDBPath(dbPath)
+	if err != nil {
+		return fmt.Errorf("error resolving database path: %w", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("database %s already exists; import into a new file", path)
+	}
+
+	f, err := os.Open(in)
+	if err != nil {
+		return fmt.Errorf("error reading database export: %w", err)
+	}
+	defer f.Close()
+
+	var dump store.Dump
+	dec := json.NewDecoder(f)
+	dec.UseNumber()
+	if err := dec.Decode(&dump); err != nil {
+		return fmt.Errorf("error decoding database export %s: %w", in, err)
+	}
+
+	dbOpts, err := dbOptions(configFile)
+	if err != nil {
+		return err
+	}
+
+	db, err := store.Open(path, dbOpts)
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	n, err := store.Import(ctx, db, &dump)
+	if cerr := db.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		// Leave no half-loaded database behind.
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			os.Remove(path + suffix)
+		}
+		return fmt.Errorf("error importing %s: %w", in, err)
+	}
+
+	fmt.Printf("Imported %d rows from %s (schema version %d) into %s\n", n, in, dump.SchemaVersion, path)
+	return nil
+}
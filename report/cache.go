@@ -0,0 +1,122 @@
+package report
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/christophberger/publer-analytics-report/insights"
+	"github.com/christophberger/publer-analytics-report/model"
+	"github.com/christophberger/publer-analytics-report/store"
+)
+
+// cachedGenerator returns the stored completion for a prompt that was sent
+// with the same generation parameters before, and stores new completions in
+// the database.
+type cachedGenerator struct {
+	db    *sql.DB
+	gen   insights.Generator
+	model string
+	// params identifies the API and the settings the completions depend
+	// on, as returned by generationParams.
+	params string
+	// progress, if set, receives a cached completion, as a streamed one
+	// is echoed while it arrives.
+	progress io.Writer
+}
+
+// generationParams returns the settings of config that a completion
+// depends on besides the prompt: the provider, endpoint, model, and
+// sampling parameters. A change to any of them must miss the cache.
+func generationParams(config *model.Config) string {
+	b, _ := json.Marshal(struct {
+		Provider    string   `json:"provider"`
+		BaseURL     string   `json:"base_url"`
+		Model       string   `json:"model"`
+		MaxTokens   int      `json:"max_tokens"`
+		Temperature *float64 `json:"temperature"`
+	}{
+		strings.ToLower(config.API.Provider),
+		config.API.BaseURL,
+		config.API.Model,
+		config.API.MaxTokens,
+		config.API.Temperature,
+	})
+	return string(b)
+}
+
+func (g *cachedGenerator) key(prompt string) string {
+	sum := sha256.Sum256([]byte(g.params + "\x00" + prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+func (g *cachedGenerator) Generate(ctx context.Context, prompt string) (string, error) {
+	key := g.key(prompt)
+	if response, ok, err := store.CachedResponse(ctx, g.db, key); err != nil {
+		slog.Warn("could not read the AI response cache", "err", err)
+	} else if ok {
+		slog.Info("using cached AI response", "key", key[:12])
+		if g.progress != nil {
+			io.WriteString(g.progress, response+"\n")
+		}
+		return response, nil
+	}
+
+	response, err := g.gen.Generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	if err := store.SaveCachedResponse(ctx, g.db, key, g.model, response); err != nil {
+		slog.Warn("could not cache the AI response", "err", err)
+	}
+	return response, nil
+}
+
+// useCache makes the AI generator of o look up and store its responses in
+// db, unless the AI is disabled.
+func (o *runOptions) useCache(db *sql.DB) {
+	if o.ai == nil || !o.cache {
+		return
+	}
+	cached := &cachedGenerator{db: db, gen: o.ai, model: o.config.API.Model, params: generationParams(o.config)}
+	if client, ok := o.ai.(*insights.Client); ok {
+		cached.progress = client.Progress
+	}
+	o.ai = cached
+}
+
+// ClearCache deletes the cached AI responses from the database at dbPath,
+// opened with the settings of configFile, and returns how many there were.
+func ClearCache(ctx context.Context, dbPath, configFile string) (int64, error) {
+	path, err := DefaultDBPath(dbPath)
+	if err != nil {
+		return 0, fmt.Errorf("error resolving database path: %w", err)
+	}
+
+	dbOpts, err := dbOptions(configFile)
+	if err != nil {
+		return 0, err
+	}
+
+	db, err := store.Open(path, dbOpts)
+	if err != nil {
+		return 0, fmt.Errorf("error opening database: %w", err)
+	}
+	defer db.Close()
+
+	if err := store.InitSchema(ctx, db); err != nil {
+		return 0, fmt.Errorf("error initializing database: %w", err)
+	}
+
+	n, err := store.ClearCache(ctx, db)
+	if err != nil {
+		return 0, fmt.Errorf("error clearing the AI response cache: %w", err)
+	}
+	return n, nil
+}
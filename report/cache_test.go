@@ -0,0 +1,112 @@
+package report
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/christophberger/publer-analytics-report/model"
+	"github.com/christophberger/publer-analytics-report/store"
+)
+
+func TestGenerationParams(t *testing.T) {
+	base := func() *model.Config {
+		c := &model.Config{}
+		c.API.Provider = "openai"
+		c.API.BaseURL = "https://api.openai.com/v1"
+		c.API.Model = "gpt-4o-mini"
+		return c
+	}
+	cold, hot := 0.0, 0.7
+
+	tests := []struct {
+		name   string
+		change func(c *model.Config)
+		same   bool
+	}{
+		{"identical", func(c *model.Config) {}, true},
+		{"provider case", func(c *model.Config) { c.API.Provider = "OpenAI" }, true},
+		{"API key variable", func(c *model.Config) { c.API.APIKeyEnv = "OTHER_KEY" }, true},
+		{"timeout", func(c *model.Config) { c.API.Timeout = 1 }, true},
+		{"provider", func(c *model.Config) { c.API.Provider = "anthropic" }, false},
+		{"base URL", func(c *model.Config) { c.API.BaseURL = "http://localhost:11434/v1" }, false},
+		{"model", func(c *model.Config) { c.API.Model = "gpt-4o" }, false},
+		{"max tokens", func(c *model.Config) { c.API.MaxTokens = 1000 }, false},
+		{"temperature", func(c *model.Config) { c.API.Temperature = &hot }, false},
+		{"zero temperature", func(c *model.Config) { c.API.Temperature = &cold }, false},
+	}
+	want := generationParams(base())
+	for _, tt := range tests {
+		c := base()
+		tt.change(c)
+		if got := generationParams(c); (got == want) != tt.same {
+			t.Errorf("%s: params %s, base %s, want same %v", tt.name, got, want, tt.same)
+		}
+	}
+}
+
+func TestCachedGenerator(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	if err := store.InitSchema(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	gen := &fakeGenerator{response: "insights"}
+	config := &model.Config{}
+	config.API.Model = "gpt-4o-mini"
+	cached := &cachedGenerator{db: db, gen: gen, model: config.API.Model, params: generationParams(config)}
+
+	for range 2 {
+		if got, err := cached.Generate(ctx, "prompt"); err != nil || got != "insights" {
+			t.Fatalf("Generate = %q, %v", got, err)
+		}
+	}
+	if len(gen.prompts) != 1 {
+		t.Errorf("the API was called %d times for the same prompt, want once", len(gen.prompts))
+	}
+
+	// A different temperature asks the API again.
+	hot := 1.2
+	config.API.Temperature = &hot
+	other := &cachedGenerator{db: db, gen: gen, model: config.API.Model, params: generationParams(config)}
+	if _, err := other.Generate(ctx, "prompt"); err != nil {
+		t.Fatal(err)
+	}
+	if len(gen.prompts) != 2 {
+		t.Errorf("the API was called %d times, want twice after changing the temperature", len(gen.prompts))
+	}
+}
+
+// TestCachedGeneratorProgress checks that a cached completion is echoed
+// like a streamed one.
+func TestCachedGeneratorProgress(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	if err := store.InitSchema(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	var progress strings.Builder
+	cached := &cachedGenerator{db: db, gen: &fakeGenerator{response: "insights"}, progress: &progress}
+	for range 2 {
+		if _, err := cached.Generate(ctx, "prompt"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The fake generator streams nothing, so only the cache hit shows.
+	if got := progress.String(); got != "insights\n" {
+		t.Errorf("progress = %q, want the cached response", got)
+	}
+}
@@ -0,0 +1,182 @@
+package report
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/christophberger/publer-analytics-report/model"
+	"github.com/christophberger/publer-analytics-report/store"
+)
+
+// CompareOptions configures a comparison of the stored periods From and
+// To, both as YYYY-MM.
+type CompareOptions struct {
+	From string
+	To   string
+	// Workspace selects the workspace. Empty picks the only one in the
+	// database.
+	Workspace string
+	DBPath    string
+	// ConfigFile supplies the database settings. Empty searches the
+	// default locations.
+	ConfigFile string
+	// Output is the file to write. Empty prints the comparison to stdout.
+	Output string
+	// Format is md (default) or json.
+	Format string
+	// Report.Top limits the hashtag movers; RateBasis and Decimals apply as
+	// in a monthly report.
+	Report ReportOptions
+	// Quiet suppresses the success line of a comparison written to a file.
+	Quiet bool
+}
+
+// ValidateCompare checks the periods and the format of a comparison. Both
+// periods are required as YYYY-MM and must differ.
+func ValidateCompare(from, to, format string) error {
+	if from == "" || to == "" {
+		return errors.New("both --from and --to are required")
+	}
+	for _, p := range []struct{ flag, value string }{{"--from", from}, {"--to", to}} {
+		if _, err := time.Parse("2006-01", p.value); err != nil {
+			return fmt.Errorf("invalid %s %q, expected YYYY-MM", p.flag, p.value)
+		}
+	}
+	if from == to {
+		return fmt.Errorf("--from and --to are both %s", from)
+	}
+	if format := cmp.Or(format, "md"); format != "md" && format != "json" {
+		return fmt.Errorf("unsupported format %q, expected md or json", format)
+	}
+	return nil
+}
+
+// RunCompare compares two stored periods of a workspace, as the compare
+// subcommand does. It reads only the database.
+func RunCompare(ctx context.Context, opts CompareOptions) error {
+	if err := ValidateCompare(opts.From, opts.To, opts.Format); err != nil {
+		return err
+	}
+	format := cmp.Or(opts.Format, "md")
+
+	path, err := DefaultDBPath(opts.DBPath)
+	if err != nil {
+		return fmt.Errorf("error resolving database path: %w", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("database %s not found", path)
+	}
+
+	dbOpts, err := dbOptions(opts.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	db, err := store.Open(path, dbOpts)
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer db.Close()
+
+	ws, err := pickWorkspace(ctx, db, opts.Workspace)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("comparing periods", "workspace", ws, "from", opts.From, "to", opts.To)
+	var periods [2]*model.OverviewData
+	for i, period := range []string{opts.From, opts.To} {
+		o, err := store.GetOverview(ctx, db, ws, period)
+		if err != nil {
+			return fmt.Errorf("error reading overview: %w", err)
+		}
+		if o == nil {
+			return fmt.Errorf("no stored period %s for %s; run periods to list them", period, ws)
+		}
+		periods[i] = rebaseRate(o, opts.Report.RateBasis)
+	}
+	from, to := periods[0], periods[1]
+
+	data := &model.ComparisonReportData{
+		Workspace: cleanWorkspaceName(ws),
+		From:      *from,
+		To:        *to,
+		Change:    *comparePeriods(to, from),
+		RateBasis: opts.Report.RateBasis,
+		Decimals:  opts.Report.Decimals,
+	}
+	changes, err := store.GetHashtagChanges(ctx, db, ws, opts.To, opts.From)
+	if err != nil {
+		return fmt.Errorf("error comparing hashtags: %w", err)
+	}
+	data.HashtagRisers, data.HashtagFallers = hashtagMovers(changes, opts.Report.Top)
+
+	var content string
+	if format == "json" {
+		content, err = renderJSONReport(data)
+	} else {
+		content, err = renderComparison(data)
+	}
+	if err != nil {
+		return fmt.Errorf("error rendering comparison: %w", err)
+	}
+
+	if opts.Output == "" {
+		_, err := os.Stdout.WriteString(content)
+		return err
+	}
+	filename, err := resolveOutputPath(opts.Output, fmt.Sprintf("%s %s vs %s.%s", data.Workspace, opts.From, opts.To, format))
+	if err != nil {
+		return fmt.Errorf("error resolving output path: %w", err)
+	}
+	if err := writeReport(content, filename); err != nil {
+		return fmt.Errorf("error writing comparison: %w", err)
+	}
+	if !opts.Quiet {
+		fmt.Printf("Comparison written successfully: %s\n", filename)
+	}
+	return nil
+}
+
+func renderComparison(data *model.ComparisonReportData) (string, error) {
+	tmpl := `# {{periodMonth .From.Period}} vs. {{periodMonth .To.Period}}
+
+For {{.Workspace}}
+
+| Metric | {{.From.Period}} | {{.To.Period}} | Change |
+| --- | ---: | ---: | --- |
+| Followers | {{num .From.Followers}} | {{num .To.Followers}} | {{followersChange .Change.FollowersChange}}{{with .Change.FollowersChangePct}}, {{percentChange .}}{{end}} |
+| Reach | {{num .From.Reach}} | {{num .To.Reach}} | {{percentChange .Change.ReachChange}} |
+| Reach Rate | {{pct .From.ReachRate}} | {{pct .To.ReachRate}} | {{percentChange .Change.ReachRateChange}} |
+| Engagements | {{num .From.Engagements}} | {{num .To.Engagements}} | {{percentChange .Change.EngagementsChange}} |
+| Engagement Rate{{with .RateBasis}} (engagements / {{.}}){{end}} | {{pct .From.EngagementRate}} | {{pct .To.EngagementRate}} | {{percentChange .Change.EngagementRateChange}} |
+{{if or .HashtagRisers .HashtagFallers}}
+## Hashtag Movers
+{{with .HashtagRisers}}
+Rising:
+{{range $i, $c := .}}
+{{add $i 1}}. {{$c.Hashtag}} (score {{printf "%.2f" $c.Score}}, {{printf "%+.2f" $c.Change}}{{if $c.New}}, new{{end}})
+{{end}}{{end}}{{with .HashtagFallers}}
+Falling:
+{{range $i, $c := .}}
+{{add $i 1}}. {{$c.Hashtag}} (score {{printf "%.2f" $c.Score}}, {{printf "%+.2f" $c.Change}}{{if $c.Dropped}}, not used in {{periodMonth $.To.Period}}{{end}})
+{{end}}{{end}}{{end}}`
+
+	t, err := template.New("compare").Funcs(reportFuncMap()).Funcs(numberFuncs(data.Decimals)).Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,81 @@
+package report
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ingestSamples stores the sample periods of June and July 2025 in the
+// database of opts.
+func ingestSamples(t *testing.T, opts Options) {
+	t.Helper()
+	opts.IngestOnly = true
+	for _, dir := range []string{"2025-06", "2025-07"} {
+		opts.Input = filepath.Join("..", "testdata", dir)
+		if err := RunContext(context.Background(), opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRunCompare(t *testing.T) {
+	opts := testOptions(t, "", nil)
+	ingestSamples(t, opts)
+
+	output := filepath.Join(t.TempDir(), "compare.md")
+	err := RunCompare(context.Background(), CompareOptions{From: "2025-06", To: "2025-07", DBPath: opts.DBPath, Output: output, Report: ReportOptions{Top: 3, Decimals: 1}, Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"| Followers | 4,707 | 4,750 | +43 new followers", "| Reach | 157 | 507 |"} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("comparison lacks %q:\n%s", want, b)
+		}
+	}
+}
+
+func TestRunCompareErrors(t *testing.T) {
+	opts := testOptions(t, "", nil)
+	ingestSamples(t, opts)
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		from, to string
+		want     string
+	}{
+		{"missing period", context.Background(), "", "2025-07", "both --from and --to are required"},
+		{"invalid period", context.Background(), "June", "2025-07", `invalid --from "June"`},
+		{"same period", context.Background(), "2025-07", "2025-07", "are both 2025-07"},
+		{"not stored", context.Background(), "2025-05", "2025-07", "no stored period 2025-05"},
+		{"canceled", canceled, "2025-06", "2025-07", "context canceled"},
+	}
+	for _, tt := range tests {
+		err := RunCompare(tt.ctx, CompareOptions{From: tt.from, To: tt.to, DBPath: opts.DBPath, Output: filepath.Join(t.TempDir(), "compare.md")})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: RunCompare error = %v, want one containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+// TestRunCompareMissingDatabase checks that compare neither creates nor
+// migrates a database it cannot find.
+func TestRunCompareMissingDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "typo.db")
+	err := RunCompare(context.Background(), CompareOptions{From: "2025-06", To: "2025-07", DBPath: path})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("RunCompare error = %v, want one saying the database is not found", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Errorf("RunCompare created %s", path)
+	}
+}
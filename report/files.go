@@ -0,0 +1,490 @@
+package report
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// filenameSeparators are the characters that separate the workspace name,
+// the export type, and the period in export filenames, besides spaces.
+const filenameSeparators = "∙·•-–—_"
+
+// datePattern matches the dates in Publer's UI exports, such as "1 Jul 2025"
+// or "Jul 1, 2025", ISO dates from API exports, and day-first numeric dates
+// like "01.07.2025" or "01-07-2025". DD/MM/YYYY dates cannot appear in file
+// names, so their slashes are expected to be replaced by one of these.
+const datePattern = `(?:\d{1,2} [A-Za-z]{3} \d{4}|[A-Za-z]{3} \d{1,2}, \d{4}|\d{4}-\d{2}-\d{2}|\d{1,2}[.-]\d{1,2}[.-]\d{4})`
+
+var dateRangePattern = regexp.MustCompile(`(` + datePattern + `)\s*[-–—_]\s*(` + datePattern + `)`)
+
+// trailingDateRangePattern matches a date range at the end of a filename.
+var trailingDateRangePattern = regexp.MustCompile(dateRangePattern.String() + `\s*$`)
+
+var startDateLayouts = []string{"2 Jan 2006", "Jan 2, 2006", "2006-01-02"}
+
+// dayFirstSeparators normalizes "01.07.2025" and "01-07-2025" to the
+// 02/01/2006 layout. ISO dates are parsed before they could be mangled.
+var dayFirstSeparators = strings.NewReplacer(".", "/", "-", "/")
+
+// splitFilename splits an export filename into the part before the period,
+// which holds the workspace name and the export type, and the period. It
+// anchors on the date range at the end rather than splitting on the
+// separators, because workspace names may contain them, too. A name with
+// the range elsewhere, such as a "(1)" copy, falls back to its last range.
+func splitFilename(filename string) (prefix, period string, err error) {
+	name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(filename), ".gz"), ".csv")
+
+	loc := trailingDateRangePattern.FindStringIndex(name)
+	if loc == nil {
+		all := dateRangePattern.FindAllStringIndex(name, -1)
+		if len(all) == 0 {
+			return name, "", fmt.Errorf("invalid filename format")
+		}
+		loc = all[len(all)-1]
+	}
+
+	prefix = strings.TrimRight(name[:loc[0]], " "+filenameSeparators)
+	return prefix, strings.TrimSpace(name[loc[0]:loc[1]]), nil
+}
+
+func periodFromFilename(filename string) (string, error) {
+	_, period, err := splitFilename(filename)
+	return period, err
+}
+
+func parseStartDate(period string) (time.Time, error) {
+	start, _, err := parseDateRange(period)
+	return start, err
+}
+
+// parseDateRange returns the first and last day of a period such as
+// "1 Jul 2025 - 31 Jul 2025".
+func parseDateRange(period string) (start, end time.Time, err error) {
+	m := dateRangePattern.FindStringSubmatch(period)
+	if m == nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date format in filename")
+	}
+	if start, err = parseRangeDate(m[1]); err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start date format: %s", m[1])
+	}
+	if end, err = parseRangeDate(m[2]); err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end date format: %s", m[2])
+	}
+	return start, end, nil
+}
+
+func parseRangeDate(s string) (time.Time, error) {
+	for _, layout := range startDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Parse("2/1/2006", dayFirstSeparators.Replace(s))
+}
+
+func startDateFromFilename(filename string) (time.Time, error) {
+	period, err := periodFromFilename(filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseStartDate(period)
+}
+
+// dateRangeFromFilename returns the first and last day of the period in
+// filename.
+func dateRangeFromFilename(filename string) (start, end time.Time, err error) {
+	period, err := periodFromFilename(filename)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return parseDateRange(period)
+}
+
+func extractDateFromFilename(filename string) (string, error) {
+	start, err := startDateFromFilename(filename)
+	if err != nil {
+		return "", err
+	}
+	return start.Format("2006-01"), nil
+}
+
+func extractPeriodFromFilename(filename string) string {
+	period, err := periodFromFilename(filename)
+	if err != nil {
+		return "Unknown Period"
+	}
+	return period
+}
+
+func extractMonthFromFilename(filename string) string {
+	start, err := startDateFromFilename(filename)
+	if err != nil {
+		return "Unknown Month"
+	}
+	return start.Format("January 2006")
+}
+
+func generateReportFilename(workspaceName, period, format string) string {
+	return fmt.Sprintf("%s %s.%s", cleanWorkspaceName(workspaceName), period, format)
+}
+
+// cleanWorkspaceName drops the "(Workspace)" suffix Publer appends to
+// workspace names.
+func cleanWorkspaceName(name string) string {
+	return strings.TrimSpace(strings.ReplaceAll(name, "(Workspace)", ""))
+}
+
+// findCSVFiles returns the overview, post insights, and hashtag analysis
+// files of param, which is a directory, one of the files, or a ZIP archive
+// holding them. Files extracted from an archive are removed by cleanup,
+// which the caller must call once it is done with them.
+func findCSVFiles(param string, latest bool) (overview, posts, hashtags string, cleanup func(), err error) {
+	info, err := os.Stat(param)
+	if err != nil {
+		return "", "", "", nil, err
+	}
+
+	switch {
+	case info.IsDir():
+		overview, posts, hashtags, err = findCSVFilesInDir(param, latest)
+	case isZipFile(param):
+		return findCSVFilesInZip(param, latest)
+	default:
+		overview, posts, hashtags, err = findCSVFilesFromFile(param, latest)
+	}
+	return overview, posts, hashtags, func() {}, err
+}
+
+func isZipFile(filename string) bool {
+	return strings.EqualFold(filepath.Ext(filename), ".zip")
+}
+
+// findCSVFilesInZip extracts the CSV exports of a ZIP archive, such as
+// Publer's "export all" download, into a temporary directory and picks
+// them from there. Entries in nested directories are found, too.
+func findCSVFilesInZip(archive string, latest bool) (overview, posts, hashtags string, cleanup func(), err error) {
+	dir, err := os.MkdirTemp("", "publer-zip-")
+	if err != nil {
+		return "", "", "", nil, err
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+
+	c, err := extractCSVFiles(archive, dir)
+	if err == nil {
+		var missing []string
+		for _, k := range []struct {
+			kind  string
+			files []string
+		}{{"Overview", c.overview}, {"Post Insights", c.posts}, {"Hashtag Analysis", c.hashtags}} {
+			if len(k.files) == 0 {
+				missing = append(missing, k.kind)
+			}
+		}
+		if len(missing) > 0 {
+			err = fmt.Errorf("archive %s is missing CSV files: %s", filepath.Base(archive), strings.Join(missing, ", "))
+		} else {
+			overview, posts, hashtags, err = c.pick(archive, latest)
+		}
+	}
+	if err != nil {
+		cleanup()
+		return "", "", "", nil, err
+	}
+	return overview, posts, hashtags, cleanup, nil
+}
+
+// extractCSVFiles copies the CSV exports of archive into dir, keeping their
+// base names, which carry the export type and the period.
+func extractCSVFiles(archive, dir string) (*csvCandidates, error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("error opening archive %s: %w", archive, err)
+	}
+	defer zr.Close()
+
+	c := &csvCandidates{}
+	seen := map[string]bool{}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		filename := path.Base(f.Name)
+		var list *[]string
+		switch {
+		case isOverviewFile(filename):
+			list = &c.overview
+		case isPostInsightsFile(filename):
+			list = &c.posts
+		case isHashtagAnalysisFile(filename):
+			list = &c.hashtags
+		default:
+			continue
+		}
+		if seen[filename] {
+			return nil, fmt.Errorf("archive %s holds %s more than once", filepath.Base(archive), filename)
+		}
+		seen[filename] = true
+
+		fp := filepath.Join(dir, filename)
+		if err := extractFile(f, fp); err != nil {
+			return nil, fmt.Errorf("error extracting %s: %w", f.Name, err)
+		}
+		*list = append(*list, fp)
+	}
+
+	return c, nil
+}
+
+func extractFile(f *zip.File, dest string) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	// Keep the archived modification time for the manifest.
+	return os.Chtimes(dest, f.Modified, f.Modified)
+}
+
+type csvCandidates struct {
+	overview, posts, hashtags []string
+}
+
+func collectCSVFiles(dir string) (*csvCandidates, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &csvCandidates{}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		filename := file.Name()
+		fp := filepath.Join(dir, filename)
+
+		if isOverviewFile(filename) {
+			c.overview = append(c.overview, fp)
+		} else if isPostInsightsFile(filename) {
+			c.posts = append(c.posts, fp)
+		} else if isHashtagAnalysisFile(filename) {
+			c.hashtags = append(c.hashtags, fp)
+		}
+	}
+
+	return c, nil
+}
+
+func pickCSVFile(kind string, matches []string, latest bool) (string, error) {
+	switch {
+	case len(matches) == 0:
+		return "", nil
+	case len(matches) == 1:
+		return matches[0], nil
+	case !latest:
+		return "", fmt.Errorf("found %d %s files, expected one: %s (use --latest to pick the most recent)", len(matches), kind, baseNames(matches))
+	}
+
+	best, bestPeriod, tied := "", "", false
+	for _, m := range matches {
+		period, _ := extractDateFromFilename(filepath.Base(m))
+		switch {
+		case best == "" || period > bestPeriod:
+			best, bestPeriod, tied = m, period, false
+		case period == bestPeriod:
+			tied = true
+		}
+	}
+	if tied {
+		return "", fmt.Errorf("found several %s files for the most recent period %s: %s", kind, bestPeriod, baseNames(matches))
+	}
+
+	return best, nil
+}
+
+func filterByPeriod(paths []string, period string) []string {
+	var same []string
+	for _, p := range paths {
+		if pp, err := extractDateFromFilename(filepath.Base(p)); err == nil && pp == period {
+			same = append(same, p)
+		}
+	}
+	if len(same) == 0 {
+		return paths
+	}
+	return same
+}
+
+func baseNames(paths []string) string {
+	names := make([]string, len(paths))
+	for i, p := range paths {
+		names[i] = filepath.Base(p)
+	}
+	return strings.Join(names, ", ")
+}
+
+func (c *csvCandidates) pick(dir string, latest bool) (string, string, string, error) {
+	overview, err := pickCSVFile("Overview", c.overview, latest)
+	if err != nil {
+		return "", "", "", err
+	}
+	posts, err := pickCSVFile("Post Insights", c.posts, latest)
+	if err != nil {
+		return "", "", "", err
+	}
+	hashtags, err := pickCSVFile("Hashtag Analysis", c.hashtags, latest)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	if overview == "" || posts == "" || hashtags == "" {
+		return "", "", "", fmt.Errorf("could not find all required CSV files in directory: %s", dir)
+	}
+
+	return overview, posts, hashtags, nil
+}
+
+func findCSVFilesInDir(dir string, latest bool) (string, string, string, error) {
+	c, err := collectCSVFiles(dir)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return c.pick(dir, latest)
+}
+
+func findCSVFilesFromFile(filePath string, latest bool) (string, string, string, error) {
+	dir := filepath.Dir(filePath)
+	if dir == "" {
+		dir = "."
+	}
+
+	base := filepath.Base(filePath)
+	if !(isOverviewFile(base) || isPostInsightsFile(base) || isHashtagAnalysisFile(base)) {
+		return "", "", "", fmt.Errorf("provided file is not a recognized CSV type: %s", base)
+	}
+
+	c, err := collectCSVFiles(dir)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	if period, err := extractDateFromFilename(base); err == nil {
+		c.overview = filterByPeriod(c.overview, period)
+		c.posts = filterByPeriod(c.posts, period)
+		c.hashtags = filterByPeriod(c.hashtags, period)
+	}
+
+	fp := filepath.Join(dir, base)
+	switch {
+	case isOverviewFile(base):
+		c.overview = []string{fp}
+	case isPostInsightsFile(base):
+		c.posts = []string{fp}
+	case isHashtagAnalysisFile(base):
+		c.hashtags = []string{fp}
+	}
+
+	return c.pick(dir, latest)
+}
+
+func checkSamePeriod(files ...string) error {
+	periods := make([]string, len(files))
+	mismatch := false
+	for i, f := range files {
+		p, err := extractDateFromFilename(filepath.Base(f))
+		if err != nil {
+			p = "unknown"
+		}
+		periods[i] = p
+		if p != periods[0] {
+			mismatch = true
+		}
+	}
+	if !mismatch {
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("CSV files cover different periods (use --force to proceed anyway):")
+	for i, f := range files {
+		fmt.Fprintf(&sb, "\n  %s: %s", periods[i], filepath.Base(f))
+	}
+	return errors.New(sb.String())
+}
+
+func isCSVFile(filename string) bool {
+	return strings.HasSuffix(filename, ".csv") || strings.HasSuffix(filename, ".csv.gz")
+}
+
+func isOverviewFile(filename string) bool {
+	return isExportType(filename, "Overview")
+}
+
+func isPostInsightsFile(filename string) bool {
+	return isExportType(filename, "Post Insights")
+}
+
+func isHashtagAnalysisFile(filename string) bool {
+	return isExportType(filename, "Hashtag Analysis")
+}
+
+// isExportType reports whether filename is a CSV export of the given type.
+// The type is the last part before the period, so that a workspace named
+// "Overview Partners" does not make all of its files look like overviews.
+func isExportType(filename, kind string) bool {
+	if !isCSVFile(filename) {
+		return false
+	}
+	prefix, _, err := splitFilename(filename)
+	if err != nil {
+		return strings.Contains(filename, kind)
+	}
+	return strings.HasSuffix(prefix, kind)
+}
+
+func workspaceDirs(parent string) ([]string, error) {
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(parent, e.Name())
+		c, err := collectCSVFiles(dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(c.overview)+len(c.posts)+len(c.hashtags) > 0 {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs, nil
+}
@@ -0,0 +1,231 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exportName returns the filename of a Publer export of kind for the
+// month of July or June 2025.
+func exportName(kind, month string) string {
+	return "ACME Inc (Workspace) ∙ " + kind + " ∙ 1 " + month + " 2025 - 30 " + month + " 2025.csv"
+}
+
+// touch creates empty files with the given names in dir.
+func touch(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPickCSVFile(t *testing.T) {
+	june, july := exportName("Overview", "Jun"), exportName("Overview", "Jul")
+	tests := []struct {
+		name    string
+		matches []string
+		latest  bool
+		want    string
+		err     string
+	}{
+		{"none", nil, false, "", ""},
+		{"one", []string{june}, false, june, ""},
+		{"several", []string{june, july}, false, "", "found 2 Overview files, expected one: " + june + ", " + july},
+		{"several with --latest", []string{july, june}, true, july, ""},
+		{"same period with --latest", []string{june, "copy of " + june}, true, "", "several Overview files for the most recent period 2025-06"},
+	}
+	for _, tt := range tests {
+		got, err := pickCSVFile("Overview", tt.matches, tt.latest)
+		if got != tt.want || (err == nil) != (tt.err == "") || (err != nil && !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: pickCSVFile = %q, %v, want %q, error %q", tt.name, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestFindCSVFilesInDir(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, dir, exportName("Post Insights", "Jul"), exportName("Hashtag Analysis", "Jul"))
+	if _, _, _, err := findCSVFilesInDir(dir, false); err == nil || !strings.Contains(err.Error(), "could not find all required CSV files") {
+		t.Errorf("without an overview: error %v, want a missing file", err)
+	}
+
+	touch(t, dir, exportName("Overview", "Jul"))
+	overview, posts, hashtags, err := findCSVFilesInDir(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(overview) != exportName("Overview", "Jul") || filepath.Base(posts) != exportName("Post Insights", "Jul") || filepath.Base(hashtags) != exportName("Hashtag Analysis", "Jul") {
+		t.Errorf("found %s, %s, %s", overview, posts, hashtags)
+	}
+
+	touch(t, dir, exportName("Overview", "Jun"))
+	if _, _, _, err := findCSVFilesInDir(dir, false); err == nil || !strings.Contains(err.Error(), exportName("Overview", "Jun")) {
+		t.Errorf("with two overviews: error %v, want one listing both", err)
+	}
+	overview, _, _, err = findCSVFilesInDir(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(overview) != exportName("Overview", "Jul") {
+		t.Errorf("with --latest: overview %s, want the July one", overview)
+	}
+}
+
+func TestCheckSamePeriod(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		err   []string
+	}{
+		{"same month", []string{exportName("Overview", "Jul"), exportName("Post Insights", "Jul"), exportName("Hashtag Analysis", "Jul")}, nil},
+		{"paths", []string{filepath.Join("a", exportName("Overview", "Jul")), filepath.Join("b", exportName("Post Insights", "Jul"))}, nil},
+		{"posts of another month", []string{exportName("Overview", "Jul"), exportName("Post Insights", "Jun"), exportName("Hashtag Analysis", "Jul")},
+			[]string{"--force", "2025-07: " + exportName("Overview", "Jul"), "2025-06: " + exportName("Post Insights", "Jun"), "2025-07: " + exportName("Hashtag Analysis", "Jul")}},
+		{"undated file", []string{exportName("Overview", "Jul"), "posts.csv"}, []string{"unknown: posts.csv"}},
+	}
+	for _, tt := range tests {
+		err := checkSamePeriod(tt.files...)
+		if (err != nil) != (tt.err != nil) {
+			t.Errorf("%s: checkSamePeriod = %v, want error %v", tt.name, err, tt.err != nil)
+			continue
+		}
+		for _, s := range tt.err {
+			if !strings.Contains(err.Error(), s) {
+				t.Errorf("%s: error %q does not contain %q", tt.name, err, s)
+			}
+		}
+	}
+}
+
+func TestSplitFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		prefix   string
+		period   string
+		month    string
+	}{
+		{"ACME ∙ Overview ∙ 1 Jul 2025 - 31 Jul 2025.csv", "ACME ∙ Overview", "1 Jul 2025 - 31 Jul 2025", "2025-07"},
+		{"ACME · Overview · 1 Jul 2025 - 31 Jul 2025.csv", "ACME · Overview", "1 Jul 2025 - 31 Jul 2025", "2025-07"},
+		{"ACME • Overview • 1 Jul 2025 - 31 Jul 2025.csv", "ACME • Overview", "1 Jul 2025 - 31 Jul 2025", "2025-07"},
+		{"ACME - Overview - 1 Jul 2025 - 31 Jul 2025.csv", "ACME - Overview", "1 Jul 2025 - 31 Jul 2025", "2025-07"},
+		{"ACME – Overview – 1 Jul 2025 – 31 Jul 2025.csv", "ACME – Overview", "1 Jul 2025 – 31 Jul 2025", "2025-07"},
+		{"ACME_Overview_Jul 1, 2025 - Jul 31, 2025.csv", "ACME_Overview", "Jul 1, 2025 - Jul 31, 2025", "2025-07"},
+		{"ACME ∙ Overview ∙ 1 Jul 2025 - 31 Jul 2025.csv.gz", "ACME ∙ Overview", "1 Jul 2025 - 31 Jul 2025", "2025-07"},
+		// The range is not at the end: the last one found applies.
+		{"ACME ∙ Overview ∙ 1 Jul 2025 - 31 Jul 2025 (1).csv", "ACME ∙ Overview", "1 Jul 2025 - 31 Jul 2025", "2025-07"},
+		{"Overview 1 Jun 2025 - 30 Jun 2025 copy 1 Jul 2025 - 31 Jul 2025 (2).csv", "Overview 1 Jun 2025 - 30 Jun 2025 copy", "1 Jul 2025 - 31 Jul 2025", "2025-07"},
+	}
+	for _, tt := range tests {
+		prefix, period, err := splitFilename(tt.filename)
+		if err != nil || prefix != tt.prefix || period != tt.period {
+			t.Errorf("splitFilename(%q) = %q, %q, %v, want %q, %q", tt.filename, prefix, period, err, tt.prefix, tt.period)
+		}
+		if month, err := extractDateFromFilename(tt.filename); err != nil || month != tt.month {
+			t.Errorf("extractDateFromFilename(%q) = %q, %v, want %q", tt.filename, month, err, tt.month)
+		}
+	}
+
+	for _, filename := range []string{"ACME ∙ Overview.csv", "ACME ∙ Overview ∙ July 2025.csv"} {
+		if _, _, err := splitFilename(filename); err == nil {
+			t.Errorf("splitFilename(%q) succeeded, want an error", filename)
+		}
+	}
+}
+
+func TestIsExportType(t *testing.T) {
+	tests := []struct {
+		filename                  string
+		overview, posts, hashtags bool
+	}{
+		{exportName("Overview", "Jul"), true, false, false},
+		{exportName("Overview", "Jul") + ".gz", true, false, false},
+		{exportName("Post Insights", "Jul") + ".gz", false, true, false},
+		{exportName("Hashtag Analysis", "Jul") + ".gz", false, false, true},
+		{exportName("Overview", "Jul") + ".zip", false, false, false},
+		{strings.TrimSuffix(exportName("Overview", "Jul"), ".csv") + ".gz", false, false, false},
+		{"Overview Partners ∙ Post Insights ∙ 1 Jul 2025 - 31 Jul 2025.csv.gz", false, true, false},
+	}
+	for _, tt := range tests {
+		if got := isOverviewFile(tt.filename); got != tt.overview {
+			t.Errorf("isOverviewFile(%q) = %v, want %v", tt.filename, got, tt.overview)
+		}
+		if got := isPostInsightsFile(tt.filename); got != tt.posts {
+			t.Errorf("isPostInsightsFile(%q) = %v, want %v", tt.filename, got, tt.posts)
+		}
+		if got := isHashtagAnalysisFile(tt.filename); got != tt.hashtags {
+			t.Errorf("isHashtagAnalysisFile(%q) = %v, want %v", tt.filename, got, tt.hashtags)
+		}
+	}
+}
+
+func TestFilenameDateFormats(t *testing.T) {
+	tests := []struct {
+		name  string
+		dates string
+	}{
+		{"Publer UI", "1 Mar 2024 - 31 Mar 2024"},
+		{"month first", "Mar 1, 2024 - Mar 31, 2024"},
+		{"ISO with underscore", "2024-03-01_2024-03-31"},
+		{"ISO with hyphen", "2024-03-01 - 2024-03-31"},
+		{"day first with dots", "01.03.2024 - 31.03.2024"},
+		{"day first with hyphens", "01-03-2024_31-03-2024"},
+		{"day first without zeros", "1.3.2024 - 31.3.2024"},
+	}
+	for _, tt := range tests {
+		filename := "ACME ∙ Overview ∙ " + tt.dates + ".csv"
+		if month, err := extractDateFromFilename(filename); err != nil || month != "2024-03" {
+			t.Errorf("%s: extractDateFromFilename(%q) = %q, %v, want 2024-03", tt.name, filename, month, err)
+		}
+		if month := extractMonthFromFilename(filename); month != "March 2024" {
+			t.Errorf("%s: extractMonthFromFilename(%q) = %q, want March 2024", tt.name, filename, month)
+		}
+		start, end, err := dateRangeFromFilename(filename)
+		if err != nil || start != time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC) || end != time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC) {
+			t.Errorf("%s: dateRangeFromFilename(%q) = %v, %v, %v, want 1 to 31 March 2024", tt.name, filename, start, end, err)
+		}
+	}
+
+	for _, dates := range []string{"2024-13-01_2024-13-31", "32.03.2024 - 31.03.2024"} {
+		filename := "ACME ∙ Overview ∙ " + dates + ".csv"
+		if month, err := extractDateFromFilename(filename); err == nil {
+			t.Errorf("extractDateFromFilename(%q) = %q, want an error", filename, month)
+		}
+		if month := extractMonthFromFilename(filename); month != "Unknown Month" {
+			t.Errorf("extractMonthFromFilename(%q) = %q, want Unknown Month", filename, month)
+		}
+	}
+}
+
+func TestWorkspaceNameWithBullet(t *testing.T) {
+	tests := []struct {
+		filename string
+		prefix   string
+	}{
+		{"Café ∙ Bar (Workspace) ∙ Overview ∙ 1 Jul 2025 - 31 Jul 2025.csv", "Café ∙ Bar (Workspace) ∙ Overview"},
+		{"A ∙ B ∙ C ∙ Overview ∙ Jul 1, 2025 - Jul 31, 2025.csv", "A ∙ B ∙ C ∙ Overview"},
+		{"Team • 1 Jun 2025 - 30 Jun 2025 ∙ Overview ∙ 1 Jul 2025 - 31 Jul 2025.csv", "Team • 1 Jun 2025 - 30 Jun 2025 ∙ Overview"},
+	}
+	for _, tt := range tests {
+		prefix, _, err := splitFilename(tt.filename)
+		if err != nil || prefix != tt.prefix {
+			t.Errorf("splitFilename(%q) = %q, %v, want %q", tt.filename, prefix, err, tt.prefix)
+		}
+		if !isOverviewFile(tt.filename) {
+			t.Errorf("isOverviewFile(%q) = false", tt.filename)
+		}
+		if period := extractPeriodFromFilename(tt.filename); !strings.HasSuffix(strings.TrimSuffix(tt.filename, ".csv"), period) {
+			t.Errorf("extractPeriodFromFilename(%q) = %q, want the trailing range", tt.filename, period)
+		}
+		if month, err := extractDateFromFilename(tt.filename); err != nil || month != "2025-07" {
+			t.Errorf("extractDateFromFilename(%q) = %q, %v, want 2025-07", tt.filename, month, err)
+		}
+		if month := extractMonthFromFilename(tt.filename); month != "July 2025" {
+			t.Errorf("extractMonthFromFilename(%q) = %q, want July 2025", tt.filename, month)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package report
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/christophberger/publer-analytics-report/model"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden reports in testdata/golden with the current output")
+
+// stubGenerator answers a prompt with its first line, so that the golden
+// reports also show which prompt each AI section was generated from.
+type stubGenerator struct{}
+
+func (stubGenerator) Generate(ctx context.Context, prompt string) (string, error) {
+	first, _, _ := strings.Cut(prompt, "\n")
+	return "Stub response to: " + first, nil
+}
+
+// TestGolden runs the pipeline on each export directory in testdata/golden
+// with a fresh database, so that no previous month is found, and compares
+// the Markdown report with the golden file next to the directory.
+func TestGolden(t *testing.T) {
+	dir := filepath.Join("..", "testdata", "golden")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		t.Run(e.Name(), func(t *testing.T) {
+			opts := testOptions(t, filepath.Join(dir, e.Name()), nil)
+			opts.NoAI, opts.Generator = false, stubGenerator{}
+			opts.Output = filepath.Join(t.TempDir(), "report.md")
+			if err := RunContext(context.Background(), opts); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(opts.Output)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			checkGolden(t, filepath.Join(dir, e.Name()+".md"), string(got))
+		})
+	}
+}
+
+// TestGoldenEmptyReport renders a report without any data, so that each
+// section shows its placeholder instead of an empty list.
+func TestGoldenEmptyReport(t *testing.T) {
+	got, err := renderFormat(&model.ReportData{}, "md", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, filepath.Join("..", "testdata", "golden", "empty-report.md"), got)
+}
+
+// checkGolden compares got with the golden file, or rewrites the file with
+// -update.
+func checkGolden(t *testing.T, golden, got string) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("report differs from %s; if the change is intended, run go test ./report -run TestGolden -update\n%s", golden, lineDiff(string(want), got))
+	}
+}
+
+// lineDiff lists the lines of want and got that differ, by line number.
+func lineDiff(want, got string) string {
+	w, g := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var sb strings.Builder
+	for i := range max(len(w), len(g)) {
+		var wl, gl string
+		if i < len(w) {
+			wl = w[i]
+		}
+		if i < len(g) {
+			gl = g[i]
+		}
+		if wl != gl {
+			fmt.Fprintf(&sb, "line %d:\n-%s\n+%s\n", i+1, wl, gl)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,33 @@
+package report
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// SampleConfig is the commented configuration file that WriteSampleConfig
+// writes. It selects the OpenAI API with its defaults, and is the
+// configuration of runs that find no config file.
+//
+//go:embed config.sample.yaml
+var SampleConfig string
+
+// WriteSampleConfig writes SampleConfig to path. It does not overwrite an
+// existing file.
+func WriteSampleConfig(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("config file %s already exists", path)
+	}
+	if err != nil {
+		return fmt.Errorf("error creating config file: %w", err)
+	}
+	if _, err := f.WriteString(SampleConfig); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,57 @@
+package report
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// languageNames maps the primary subtags of common BCP 47 language tags to
+// the English names used in the prompt instruction.
+var languageNames = map[string]string{
+	"da": "Danish",
+	"de": "German",
+	"en": "English",
+	"es": "Spanish",
+	"fi": "Finnish",
+	"fr": "French",
+	"it": "Italian",
+	"ja": "Japanese",
+	"nb": "Norwegian",
+	"nl": "Dutch",
+	"no": "Norwegian",
+	"pl": "Polish",
+	"pt": "Portuguese",
+	"sv": "Swedish",
+	"tr": "Turkish",
+	"zh": "Chinese",
+}
+
+// languagePattern accepts BCP 47-like tags such as "de" or "pt-BR", and
+// plain language names such as "German" or "Swiss German". Anything else
+// could smuggle further instructions into the prompts.
+var languagePattern = regexp.MustCompile(`^[A-Za-z]{2,}(?:[ -][A-Za-z0-9]{2,8})*$`)
+
+// ParseLanguage returns the name of the language the AI texts are to be
+// written in, for a language tag or name. Tags with a region keep it, as in
+// "Portuguese (pt-BR)", and unknown tags or names are passed through.
+// Empty returns empty, which keeps the prompts' language.
+func ParseLanguage(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	if len(s) > 35 || !languagePattern.MatchString(s) {
+		return "", fmt.Errorf("invalid language %q, expected a tag such as de or pt-BR, or a name such as German", s)
+	}
+
+	primary, region, _ := strings.Cut(s, "-")
+	name, ok := languageNames[strings.ToLower(primary)]
+	switch {
+	case !ok:
+		return s, nil
+	case region != "":
+		return fmt.Sprintf("%s (%s)", name, s), nil
+	}
+	return name, nil
+}
@@ -0,0 +1,119 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/christophberger/publer-analytics-report/model"
+)
+
+// Manifest records what produced a report: the CSV files it was built
+// from, the configuration, whether the AI texts were generated, and the
+// files written. It is written next to the report with --manifest.
+type Manifest struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	// Input is the file, directory, or archive the CSV files were found in,
+	// or "-" for stdin.
+	Input     string         `json:"input"`
+	Files     []ManifestFile `json:"files"`
+	Workspace string         `json:"workspace"`
+	// Period is the month as YYYY-MM, and PeriodRange the date range from
+	// the filenames.
+	Period      string `json:"period"`
+	PeriodRange string `json:"period_range"`
+	Database    string `json:"database"`
+	// Config is the configuration file, empty if none was used.
+	Config   string `json:"config,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	// AI is "generated", "skipped", or "failed".
+	AI      string   `json:"ai"`
+	Outputs []string `json:"outputs"`
+}
+
+// ManifestFile describes one CSV file a report was built from. A file read
+// from stdin is named "stdin" and has no size or modification time.
+type ManifestFile struct {
+	Type    string    `json:"type"`
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time,omitzero"`
+}
+
+// now returns the current time. Tests replace it to freeze the clock.
+var now = time.Now
+
+// newManifest describes the report built from the three CSV files. Skipped
+// files are left out.
+func newManifest(data *model.ReportData, input, overviewFile, postsFile, hashtagFile string, outputs []string, opts *runOptions) (*Manifest, error) {
+	m := &Manifest{
+		GeneratedAt: now().UTC().Truncate(time.Second),
+		Input:       input,
+		Workspace:   data.Workspace,
+		PeriodRange: data.Period,
+		Database:    opts.dbPath,
+		Config:      opts.configPath,
+		AI:          "generated",
+		Outputs:     outputs,
+	}
+	m.Period = data.PeriodKey
+	// Relative paths would be meaningless once the working directory is
+	// forgotten.
+	for _, p := range []*string{&m.Input, &m.Config} {
+		if abs, err := filepath.Abs(*p); *p != "" && *p != stdinName && err == nil {
+			*p = abs
+		}
+	}
+
+	switch {
+	case opts.noAI:
+		m.AI = "skipped"
+	case data.AIWarning != "":
+		m.AI = "failed"
+	}
+	if !opts.noAI {
+		m.Provider = opts.config.API.Provider
+		m.Model = opts.config.API.Model
+	}
+
+	for _, f := range []struct{ kind, path string }{
+		{"overview", overviewFile},
+		{"posts", postsFile},
+		{"hashtags", hashtagFile},
+	} {
+		switch f.path {
+		case "":
+			continue
+		case stdinName:
+			m.Files = append(m.Files, ManifestFile{Type: f.kind, Name: "stdin"})
+			continue
+		}
+		info, err := os.Stat(f.path)
+		if err != nil {
+			return nil, err
+		}
+		m.Files = append(m.Files, ManifestFile{
+			Type:    f.kind,
+			Name:    filepath.Base(f.path),
+			Size:    info.Size(),
+			ModTime: info.ModTime().UTC(),
+		})
+	}
+	return m, nil
+}
+
+// manifestFilename returns the manifest's filename for the report file.
+func manifestFilename(reportFilename string) string {
+	return withExt(reportFilename, "manifest.json")
+}
+
+func writeManifest(m *Manifest, filename string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding manifest: %w", err)
+	}
+	return writeReport(string(b)+"\n", filename)
+}
@@ -0,0 +1,46 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestManifest(t *testing.T) {
+	// Just after midnight on New Year's Day in Berlin is still the old year
+	// in UTC.
+	defer func(old func() time.Time) { now = old }(now)
+	now = func() time.Time {
+		return time.Date(2026, time.January, 1, 0, 30, 0, 999, time.FixedZone("CET", 3600))
+	}
+
+	opts := testOptions(t, filepath.Join("..", "testdata", "2025-07"), nil)
+	opts.Manifest = true
+	if err := Run(opts); err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(opts.Output, "*.manifest.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("manifest files %v, %v, want one", files, err)
+	}
+	b, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := time.Date(2025, time.December, 31, 23, 30, 0, 0, time.UTC); !m.GeneratedAt.Equal(want) {
+		t.Errorf("GeneratedAt = %v, want %v", m.GeneratedAt, want)
+	}
+	if m.Period != "2025-07" || m.AI != "skipped" || len(m.Files) != 3 {
+		t.Errorf("manifest period %q, AI %q, %d files, want 2025-07, skipped, 3 files", m.Period, m.AI, len(m.Files))
+	}
+	if !filepath.IsAbs(m.Input) {
+		t.Errorf("Input %q is not absolute", m.Input)
+	}
+}
@@ -0,0 +1,71 @@
+package report
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/christophberger/publer-analytics-report/model"
+)
+
+// runMetrics holds the key numbers of one report for the metrics file.
+type runMetrics struct {
+	workspace string
+	period    string
+	// posts and hashtags are the numbers of rows parsed from the export.
+	posts    int
+	hashtags int
+	reach    int
+	// reachChange is the change of reach in percent, if hasPrevious.
+	reachChange float64
+	hasPrevious bool
+	aiCalled    bool
+}
+
+func newRunMetrics(data *model.ReportData, period string, posts []model.PostData, hashtags []model.HashtagData, aiCalled bool) runMetrics {
+	return runMetrics{
+		workspace:   data.Workspace,
+		period:      period,
+		posts:       len(posts),
+		hashtags:    len(hashtags),
+		reach:       data.Reach,
+		reachChange: data.ReachChange,
+		hasPrevious: data.HasPrevious,
+		aiCalled:    aiCalled,
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatMetrics renders the metrics in the Prometheus text format, one
+// "name{labels} value" line per number, as read by the textfile collector
+// of the node exporter. The names are stable. reach_change_pct is left out
+// of reports without a previous period, so that it cannot be mistaken for
+// a change of zero.
+func formatMetrics(metrics []runMetrics) string {
+	var sb strings.Builder
+	for _, m := range metrics {
+		labels := fmt.Sprintf(`{workspace="%s",period="%s"}`, labelEscaper.Replace(m.workspace), labelEscaper.Replace(m.period))
+		line := func(name, value string) {
+			sb.WriteString(name + labels + " " + value + "\n")
+		}
+		line("posts_parsed", strconv.Itoa(m.posts))
+		line("hashtags_parsed", strconv.Itoa(m.hashtags))
+		line("reach", strconv.Itoa(m.reach))
+		if m.hasPrevious {
+			line("reach_change_pct", strconv.FormatFloat(m.reachChange, 'f', 2, 64))
+		}
+		ai := "0"
+		if m.aiCalled {
+			ai = "1"
+		}
+		line("ai_called", ai)
+	}
+	return sb.String()
+}
+
+// writeMetrics writes the metrics of the reports of a run to filename,
+// replacing it as a whole so that a scraper never sees a partial file.
+func writeMetrics(metrics []runMetrics, filename string) error {
+	return writeReport(formatMetrics(metrics), filename)
+}
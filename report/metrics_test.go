@@ -0,0 +1,49 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMetricsCountParsedRows checks that the metrics count the rows of the
+// export, including those that the database stores only once.
+func TestMetricsCountParsedRows(t *testing.T) {
+	input := t.TempDir()
+	files, err := filepath.Glob(filepath.Join("..", "testdata", "2025-07", "*.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Repeat the last row, which is a post or a hashtag the database
+		// already holds then.
+		if !strings.Contains(f, "Overview") {
+			lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+			data = []byte(strings.Join(append(lines, lines[len(lines)-1]), "\n") + "\n")
+		}
+		if err := os.WriteFile(filepath.Join(input, filepath.Base(f)), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := testOptions(t, input, nil)
+	opts.MetricsFile = filepath.Join(t.TempDir(), "publer.prom")
+	if err := Run(opts); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(opts.MetricsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := `{workspace="ACME Inc",period="2025-07"}`
+	for _, want := range []string{"posts_parsed" + labels + " 24\n", "hashtags_parsed" + labels + " 38\n"} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("metrics lack %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package report
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/christophberger/publer-analytics-report/store"
+)
+
+// Migrate applies the pending schema migrations to the database at dbPath,
+// opened with the settings of configFile, and prints each of them.
+func Migrate(ctx context.Context, dbPath, configFile string) error {
+	path, err := DefaultDBPath(dbPath)
+	if err != nil {
+		return fmt.Errorf("error resolving database path: %w", err)
+	}
+
+	dbOpts, err := dbOptions(configFile)
+	if err != nil {
+		return err
+	}
+
+	db, err := store.Open(path, dbOpts)
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer db.Close()
+
+	from, err := store.SchemaVersion(ctx, db)
+	if err != nil {
+		return fmt.Errorf("error reading schema version: %w", err)
+	}
+
+	applied, err := store.Migrate(ctx, db)
+	for _, name := range applied {
+		fmt.Printf("Applied migration: %s\n", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		fmt.Printf("%s is up to date at schema version %d\n", path, from)
+		return nil
+	}
+	fmt.Printf("Migrated %s from schema version %d to %d\n", path, from, store.LatestSchemaVersion())
+	return nil
+}
@@ -0,0 +1,52 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// openReport opens filename for the user: an HTML report in the default
+// browser, any other format in $EDITOR if set, and otherwise with the
+// platform's default handler. A missing opener is only a warning, because
+// the report has been written already.
+func openReport(filename string) {
+	if err := openFile(filename); err != nil {
+		slog.Warn("could not open report", "file", filename, "err", err)
+	}
+}
+
+func openFile(filename string) error {
+	if editor := strings.Fields(os.Getenv("EDITOR")); len(editor) > 0 && !strings.EqualFold(filepath.Ext(filename), ".html") {
+		// A terminal editor needs the terminal, and the run waits for it.
+		cmd := exec.Command(editor[0], append(editor[1:], filename)...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		return cmd.Run()
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", filename)
+	case "windows":
+		// Not cmd's start, which would interpret characters such as & in
+		// the filename.
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", filename)
+	default:
+		cmd = exec.Command("xdg-open", filename)
+	}
+	if err := cmd.Start(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return fmt.Errorf("%s not found", cmd.Path)
+		}
+		return err
+	}
+	// The handler hands the file to a GUI application and exits.
+	go cmd.Wait()
+	return nil
+}
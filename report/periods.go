@@ -0,0 +1,51 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/christophberger/publer-analytics-report/store"
+)
+
+// ListPeriods prints the stored periods of the database at dbPath, one
+// tab-separated line per workspace and period with the number of stored
+// posts, hashtags, and countries. It does not modify the database, which is
+// opened with the settings of configFile.
+func ListPeriods(ctx context.Context, dbPath, configFile, workspace string) error {
+	path, err := DefaultDBPath(dbPath)
+	if err != nil {
+		return fmt.Errorf("error resolving database path: %w", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("database %s not found", path)
+	}
+
+	dbOpts, err := dbOptions(configFile)
+	if err != nil {
+		return err
+	}
+
+	db, err := store.Open(path, dbOpts)
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer db.Close()
+
+	periods, err := store.ListPeriods(ctx, db, workspace)
+	if err != nil {
+		return fmt.Errorf("error listing periods: %w", err)
+	}
+	if len(periods) == 0 {
+		if workspace != "" {
+			return fmt.Errorf("no stored periods for %s", workspace)
+		}
+		return fmt.Errorf("no stored periods in %s", path)
+	}
+
+	fmt.Println("workspace\tperiod\tposts\thashtags\tcountries")
+	for _, p := range periods {
+		fmt.Printf("%s\t%s\t%d\t%d\t%d\n", p.Workspace, p.Period, p.Posts, p.Hashtags, p.Countries)
+	}
+	return nil
+}
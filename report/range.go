@@ -0,0 +1,231 @@
+package report
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/christophberger/publer-analytics-report/model"
+	"github.com/christophberger/publer-analytics-report/store"
+)
+
+// RangeOptions configures a report over the stored periods from Since to
+// Until, both as YYYY-MM.
+type RangeOptions struct {
+	Since string
+	Until string
+	// Workspace selects the workspace. Empty picks the only one in the
+	// database.
+	Workspace string
+	DBPath    string
+	// ConfigFile supplies the database settings. Empty searches the
+	// default locations.
+	ConfigFile string
+	Output     string
+	// Format is md (default) or json.
+	Format string
+	Report ReportOptions
+}
+
+// RunRange writes a report that aggregates the stored periods of a range.
+func RunRange(ctx context.Context, opts RangeOptions) error {
+	if err := validateRange(opts.Since, opts.Until); err != nil {
+		return err
+	}
+	format := cmp.Or(opts.Format, "md")
+	if format != "md" && format != "json" {
+		return fmt.Errorf("unsupported format %q, expected md or json", format)
+	}
+
+	path, err := DefaultDBPath(opts.DBPath)
+	if err != nil {
+		return fmt.Errorf("error resolving database path: %w", err)
+	}
+
+	dbOpts, err := dbOptions(opts.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	db, err := store.Open(path, dbOpts)
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer db.Close()
+
+	if err := store.InitSchema(ctx, db); err != nil {
+		return fmt.Errorf("error initializing database: %w", err)
+	}
+
+	ws, err := pickWorkspace(ctx, db, opts.Workspace)
+	if err != nil {
+		return err
+	}
+
+	data, err := prepareRangeData(ctx, db, ws, opts.Since, opts.Until, opts.Report)
+	if err != nil {
+		return err
+	}
+
+	var content string
+	if format == "json" {
+		content, err = renderJSONReport(data)
+	} else {
+		content, err = renderRangeReport(data)
+	}
+	if err != nil {
+		return fmt.Errorf("error rendering report: %w", err)
+	}
+
+	filename, err := resolveOutputPath(opts.Output, fmt.Sprintf("%s %s to %s.%s", cleanWorkspaceName(ws), opts.Since, opts.Until, format))
+	if err != nil {
+		return fmt.Errorf("error resolving output path: %w", err)
+	}
+	if err := writeReport(content, filename); err != nil {
+		return fmt.Errorf("error writing report: %w", err)
+	}
+	fmt.Printf("Report generated successfully: %s\n", filename)
+	return nil
+}
+
+func validateRange(since, until string) error {
+	if since == "" || until == "" {
+		return errors.New("both --since and --until are required")
+	}
+	s, err := time.Parse("2006-01", since)
+	if err != nil {
+		return fmt.Errorf("invalid --since %q, expected YYYY-MM", since)
+	}
+	u, err := time.Parse("2006-01", until)
+	if err != nil {
+		return fmt.Errorf("invalid --until %q, expected YYYY-MM", until)
+	}
+	if s.After(u) {
+		return fmt.Errorf("--since %s is after --until %s", since, until)
+	}
+	return nil
+}
+
+// pickWorkspace returns name, or the only workspace in the database if name
+// is empty.
+func pickWorkspace(ctx context.Context, db *sql.DB, name string) (string, error) {
+	if name != "" {
+		return name, nil
+	}
+	workspaces, err := store.ListWorkspaces(ctx, db)
+	if err != nil {
+		return "", fmt.Errorf("error listing workspaces: %w", err)
+	}
+	switch len(workspaces) {
+	case 0:
+		return "", errors.New("the database holds no workspaces")
+	case 1:
+		return workspaces[0], nil
+	}
+	return "", fmt.Errorf("the database holds several workspaces, pick one with --workspace: %s", strings.Join(workspaces, ", "))
+}
+
+func prepareRangeData(ctx context.Context, db *sql.DB, workspace, since, until string, opts ReportOptions) (*model.RangeReportData, error) {
+	periods, err := store.GetOverviewRange(ctx, db, workspace, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("error reading overviews: %w", err)
+	}
+	if len(periods) == 0 {
+		return nil, fmt.Errorf("no stored periods for %s between %s and %s", workspace, since, until)
+	}
+
+	data := &model.RangeReportData{
+		Workspace:     workspace,
+		Since:         since,
+		Until:         until,
+		Periods:       periods,
+		RankBy:        cmp.Or(opts.RankBy, DefaultRankBy),
+		HashtagRankBy: cmp.Or(opts.HashtagRankBy, DefaultHashtagRankBy),
+		Decimals:      opts.Decimals,
+	}
+
+	var reachRate, engagementRate float64
+	for _, p := range periods {
+		data.TotalReach += p.Reach
+		data.TotalEngagements += p.Engagements
+		reachRate += p.ReachRate
+		engagementRate += p.EngagementRate
+	}
+	n := float64(len(periods))
+	data.AvgReach = float64(data.TotalReach) / n
+	data.AvgEngagements = float64(data.TotalEngagements) / n
+	data.AvgReachRate = reachRate / n
+	data.AvgEngagementRate = engagementRate / n
+	data.Followers = periods[len(periods)-1].Followers
+	data.FollowersChange = data.Followers - periods[0].Followers
+
+	posts, err := store.GetPostsRange(ctx, db, workspace, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("error reading posts: %w", err)
+	}
+	posts = filterPostsByType(posts, opts.PostTypes)
+	rankPosts(posts, data.RankBy)
+	data.TopPosts = topN(posts, opts.Top)
+
+	hashtags, err := store.GetHashtagTotals(ctx, db, workspace, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("error reading hashtags: %w", err)
+	}
+	rankHashtags(hashtags, data.HashtagRankBy)
+	data.TopHashtags = topN(hashtags, opts.Top)
+
+	return data, nil
+}
+
+func renderRangeReport(data *model.RangeReportData) (string, error) {
+	tmpl := `# {{periodMonth .Since}} to {{periodMonth .Until}} KPIs
+
+For {{.Workspace}}, {{len .Periods}} stored periods
+
+## Summary
+
+- Followers: {{num .Followers}} ({{followersChange .FollowersChange}})
+- Total Reach: {{num .TotalReach}} (average {{num .AvgReach}} per period)
+- Total Engagements: {{num .TotalEngagements}} (average {{num .AvgEngagements}} per period)
+- Average Reach Rate: {{pct .AvgReachRate}}
+- Average Engagement Rate: {{pct .AvgEngagementRate}}
+
+## Periods
+
+| Period | Followers | Reach | Engagements | Engagement Rate |
+| --- | ---: | ---: | ---: | ---: |
+{{range .Periods}}| {{.Period}} | {{num .Followers}} | {{num .Reach}} | {{num .Engagements}} | {{pct .EngagementRate}} |
+{{end}}
+## Top-Performing Posts by {{rankTitle .RankBy}}
+
+{{range $i, $post := .TopPosts}}
+{{add $i 1}}. {{mdLink (truncateWords $post.PostText 50) $post.PostLink}}{{with platform $post.SocialNetwork}} — {{.}}{{end}} ({{num (rankValue $.RankBy $post)}} {{rankUnit $.RankBy}}{{if $post.EngagementRate}}, {{pct $post.EngagementRate}} engagement rate{{end}}, {{$post.Date}})
+{{else}}
+No data available for this period.
+{{end}}
+
+## Top Hashtags by Total {{hashtagRankTitle .HashtagRankBy}}
+
+{{range $i, $hashtag := .TopHashtags}}
+{{add $i 1}}. {{$hashtag.Hashtag}} (score {{printf "%.2f" $hashtag.Score}}, reach {{num $hashtag.Reach}}, {{num (hashtagEngagement $hashtag)}} engagements)
+{{else}}
+No data available for this period.
+{{end}}
+`
+
+	t, err := template.New("range").Funcs(reportFuncMap()).Funcs(numberFuncs(data.Decimals)).Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
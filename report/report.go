@@ -0,0 +1,1094 @@
+package report
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"log/slog"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/christophberger/publer-analytics-report/model"
+	"github.com/christophberger/publer-analytics-report/store"
+)
+
+type ReportOptions struct {
+	PostTypes    []string
+	Top          int
+	YearOverYear bool
+	History      int
+	RankBy       string
+	// Network limits the posts to one social network. Empty keeps all.
+	Network       string
+	HashtagRankBy string
+	// RecomputeRates reports the engagement rate as engagements / reach
+	// instead of the rate in the overview CSV. It is the same as RateBasis
+	// "reach".
+	RecomputeRates bool
+	// RateBasis recomputes the engagement rate as engagements per reach or
+	// per follower, as returned by ParseRateBasis, for the current and the
+	// compared periods. Empty keeps the rate in the overview CSV, which
+	// Publer computes per reach.
+	RateBasis string
+	// Redact replaces the text of each top post with a placeholder such as
+	// "[Post #1]" and drops its link, in the reports and the AI prompts.
+	Redact bool
+	// Language is the language the AI texts are requested in, as returned
+	// by ParseLanguage. Empty keeps the language of the prompts.
+	Language string
+	// Decimals is the number of decimal places of the percentages and
+	// averages in the rendered reports. The JSON and CSV formats keep the
+	// full values.
+	Decimals int
+}
+
+// DefaultDecimals is the number of decimal places the command-line flags
+// default to.
+const DefaultDecimals = 1
+
+// DefaultPostTypes limits the top-posts ranking to "Status" posts, as the
+// report specification asks for. All post types are still parsed and stored;
+// use --post-types=all to rank every type.
+const DefaultPostTypes = "Status"
+
+// ParsePostTypes returns the post types of a comma-separated --post-types
+// list, or nil for "all", which ranks every type.
+func ParsePostTypes(s string) []string {
+	if strings.EqualFold(strings.TrimSpace(s), "all") {
+		return nil
+	}
+	var types []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+type rankMetric struct {
+	title string
+	unit  string
+	value func(model.PostData) int
+}
+
+// rankMetrics holds the post metrics that --rank-by can sort the top posts by.
+var rankMetrics = map[string]rankMetric{
+	"reactions":   {"Reactions", "reactions", func(p model.PostData) int { return p.Reactions }},
+	"engagements": {"Engagements", "engagements", func(p model.PostData) int { return p.Reactions + p.Comments + p.Shares }},
+	"reach":       {"Reach", "reached", func(p model.PostData) int { return p.Reach }},
+	"clicks":      {"Link Clicks", "link clicks", func(p model.PostData) int { return p.LinkClicks }},
+}
+
+const DefaultRankBy = "reactions"
+
+// ParseRankBy returns the metric for --rank-by to rank the top posts by:
+// reactions, engagements, reach, or clicks.
+func ParseRankBy(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if _, ok := rankMetrics[s]; !ok {
+		return "", fmt.Errorf("unsupported ranking %q, expected reactions, engagements, reach, or clicks", s)
+	}
+	return s, nil
+}
+
+// lookupRankMetric returns the metric for name, falling back to reactions.
+func lookupRankMetric(name string) rankMetric {
+	if m, ok := rankMetrics[name]; ok {
+		return m
+	}
+	return rankMetrics[DefaultRankBy]
+}
+
+func rankPosts(posts []model.PostData, by string) {
+	value := lookupRankMetric(by).value
+	sort.SliceStable(posts, func(i, j int) bool { return value(posts[i]) > value(posts[j]) })
+}
+
+// hashtagRankMetrics holds the hashtag metrics that --hashtag-rank-by can
+// sort the top hashtags by.
+var hashtagRankMetrics = map[string]struct {
+	title string
+	value func(model.HashtagData) float64
+}{
+	"score":      {"Score", func(h model.HashtagData) float64 { return h.Score }},
+	"reach":      {"Reach", func(h model.HashtagData) float64 { return float64(h.Reach) }},
+	"engagement": {"Engagement", func(h model.HashtagData) float64 { return float64(hashtagEngagement(h)) }},
+}
+
+const DefaultHashtagRankBy = "score"
+
+// ParseHashtagRankBy returns the metric for --hashtag-rank-by to rank the
+// top hashtags by: score, reach, or engagement.
+func ParseHashtagRankBy(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if _, ok := hashtagRankMetrics[s]; !ok {
+		return "", fmt.Errorf("unsupported hashtag ranking %q, expected score, reach, or engagement", s)
+	}
+	return s, nil
+}
+
+func hashtagEngagement(h model.HashtagData) int {
+	return h.Reactions + h.Comments + h.Shares
+}
+
+func rankHashtags(hashtags []model.HashtagData, by string) {
+	m, ok := hashtagRankMetrics[by]
+	if !ok {
+		m = hashtagRankMetrics[DefaultHashtagRankBy]
+	}
+	sort.SliceStable(hashtags, func(i, j int) bool { return m.value(hashtags[i]) > m.value(hashtags[j]) })
+}
+
+// hashtagMovers splits changes, which are ordered by descending change, into
+// the n biggest risers and the n biggest fallers.
+func hashtagMovers(changes []model.HashtagChange, n int) (risers, fallers []model.HashtagChange) {
+	for _, c := range changes {
+		if c.Change > 0 {
+			risers = append(risers, c)
+		}
+	}
+	for i := len(changes) - 1; i >= 0; i-- {
+		if changes[i].Change < 0 {
+			fallers = append(fallers, changes[i])
+		}
+	}
+	return topN(risers, n), topN(fallers, n)
+}
+
+func filterPostsByType(posts []model.PostData, types []string) []model.PostData {
+	if len(types) == 0 {
+		return posts
+	}
+	var filtered []model.PostData
+	for _, p := range posts {
+		for _, t := range types {
+			if strings.EqualFold(p.PostType, t) {
+				filtered = append(filtered, p)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// filterPostsByNetwork keeps the posts of one social network, accepting both
+// Publer's spelling and the usual one, such as "Linkedin" and "LinkedIn".
+func filterPostsByNetwork(posts []model.PostData, network string) []model.PostData {
+	if network == "" {
+		return posts
+	}
+	var filtered []model.PostData
+	for _, p := range posts {
+		if strings.EqualFold(platformName(p.SocialNetwork), platformName(network)) {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+func topN[T any](items []T, n int) []T {
+	if n <= 0 || len(items) <= n {
+		return items
+	}
+	return items[:n]
+}
+
+// deriveMetrics computes the per-post and per-day averages of a period from
+// all its posts. It must run before the posts are cut down to the top ones.
+// The per-day values count the days from the overview's start to end date,
+// or the days of the month period if the dates are unknown.
+func deriveMetrics(overview *model.OverviewData, posts []model.PostData, period string) model.DerivedMetrics {
+	d := model.DerivedMetrics{Posts: len(posts)}
+	if n := float64(len(posts)); n > 0 {
+		var reactions, comments, shares int
+		for _, p := range posts {
+			reactions += p.Reactions
+			comments += p.Comments
+			shares += p.Shares
+		}
+		d.AvgReactions = float64(reactions) / n
+		d.AvgComments = float64(comments) / n
+		d.AvgShares = float64(shares) / n
+	}
+	if overview.Followers > 0 {
+		d.ReachPerFollower = float64(overview.Reach) / float64(overview.Followers)
+	}
+	var days float64
+	if start, end := overview.PeriodStart, overview.PeriodEnd; !start.IsZero() && !end.Before(start) {
+		days = math.Round(end.Sub(start).Hours()/24) + 1
+	} else if t, err := time.Parse("2006-01", period); err == nil {
+		days = float64(t.AddDate(0, 1, -1).Day())
+	}
+	if days > 0 {
+		d.PostsPerDay = float64(len(posts)) / days
+		d.EngagementsPerDay = float64(overview.Engagements) / days
+	}
+	return d
+}
+
+// bestTimesSlots is the number of slots the Best Posting Times section
+// lists.
+const bestTimesSlots = 3
+
+// bestPostingTimes buckets the posts by the weekday and hour they were
+// published and returns the n slots with the highest average engagements,
+// with more posts breaking ties. Posts without a date are left out, so
+// the result is empty if no post has one. Like deriveMetrics, it must run
+// on all posts of the period.
+func bestPostingTimes(posts []model.PostData, n int) []model.PostingSlot {
+	type key struct {
+		day  time.Weekday
+		hour int
+	}
+	type sums struct{ posts, reactions, engagements int }
+	buckets := map[key]*sums{}
+	for _, p := range posts {
+		if p.PostedAt.IsZero() {
+			continue
+		}
+		k := key{p.PostedAt.Weekday(), p.PostedAt.Hour()}
+		s := buckets[k]
+		if s == nil {
+			s = &sums{}
+			buckets[k] = s
+		}
+		s.posts++
+		s.reactions += p.Reactions
+		s.engagements += p.Reactions + p.Comments + p.Shares
+	}
+
+	keys := slices.Collect(maps.Keys(buckets))
+	slices.SortFunc(keys, func(a, b key) int {
+		sa, sb := buckets[a], buckets[b]
+		return cmp.Or(
+			cmp.Compare(float64(sb.engagements)/float64(sb.posts), float64(sa.engagements)/float64(sa.posts)),
+			cmp.Compare(sb.posts, sa.posts),
+			cmp.Compare(a.day, b.day),
+			cmp.Compare(a.hour, b.hour),
+		)
+	})
+
+	var slots []model.PostingSlot
+	for _, k := range topN(keys, n) {
+		s := buckets[k]
+		slots = append(slots, model.PostingSlot{
+			Weekday:        k.day.String(),
+			Hour:           k.hour,
+			Posts:          s.posts,
+			AvgReactions:   float64(s.reactions) / float64(s.posts),
+			AvgEngagements: float64(s.engagements) / float64(s.posts),
+		})
+	}
+	return slots
+}
+
+// redactPosts returns a copy of the top posts with their text replaced by
+// their rank, as in "[Post #1]", and without their links, which would lead
+// to the text. The metrics are kept.
+func redactPosts(posts []model.PostData) []model.PostData {
+	redacted := slices.Clone(posts)
+	for i := range redacted {
+		redacted[i].PostText = fmt.Sprintf("[Post #%d]", i+1)
+		redacted[i].PostLink = ""
+	}
+	return redacted
+}
+
+func periodOffset(period string, months int) (string, error) {
+	t, err := time.Parse("2006-01", period)
+	if err != nil {
+		return "", err
+	}
+	return t.AddDate(0, months, 0).Format("2006-01"), nil
+}
+
+func periodMonth(period string) string {
+	t, err := time.Parse("2006-01", period)
+	if err != nil {
+		return period
+	}
+	return t.Format("January 2006")
+}
+
+// compareOverview compares curr with the stored period months away. The
+// stored engagement rate is recomputed on basis, if set, like curr's.
+func compareOverview(ctx context.Context, db *sql.DB, curr *model.OverviewData, period string, months int, basis string) *model.Comparison {
+	other, err := periodOffset(period, months)
+	if err != nil {
+		return nil
+	}
+	prev, err := store.GetOverview(ctx, db, curr.WorkspaceName, other)
+	if err != nil || prev == nil {
+		return nil
+	}
+	return comparePeriods(curr, rebaseRate(prev, basis))
+}
+
+// comparePeriods returns the changes from prev to curr.
+func comparePeriods(curr, prev *model.OverviewData) *model.Comparison {
+	c := &model.Comparison{
+		Period:          prev.Period,
+		Month:           periodMonth(prev.Period),
+		FollowersChange: curr.Followers - prev.Followers,
+	}
+	if prev.Followers > 0 {
+		pct := float64(c.FollowersChange) * 100.0 / float64(prev.Followers)
+		c.FollowersChangePct = &pct
+	}
+	if prev.Reach > 0 {
+		c.ReachChange = float64(curr.Reach-prev.Reach) * 100.0 / float64(prev.Reach)
+	}
+	if prev.ReachRate > 0 {
+		c.ReachRateChange = (curr.ReachRate - prev.ReachRate) * 100.0 / prev.ReachRate
+	}
+	if prev.Engagements > 0 {
+		c.EngagementsChange = float64(curr.Engagements-prev.Engagements) * 100.0 / float64(prev.Engagements)
+	}
+	if prev.EngagementRate > 0 {
+		c.EngagementRateChange = (curr.EngagementRate - prev.EngagementRate) * 100.0 / prev.EngagementRate
+	}
+	return c
+}
+
+// Engagement rate bases for ReportOptions.RateBasis.
+const (
+	RateBasisReach     = "reach"
+	RateBasisFollowers = "followers"
+)
+
+// ParseRateBasis returns the denominator for --engagement-rate-basis,
+// reach or followers. Empty keeps Publer's rate.
+func ParseRateBasis(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "", RateBasisReach, RateBasisFollowers:
+		return s, nil
+	}
+	return "", fmt.Errorf("unsupported engagement rate basis %q, expected reach or followers", s)
+}
+
+// engagementRate returns the engagements of o per reach or per follower, in
+// percent, or false if the denominator is zero.
+func engagementRate(o *model.OverviewData, basis string) (float64, bool) {
+	denom := o.Reach
+	if basis == RateBasisFollowers {
+		denom = o.Followers
+	}
+	if denom <= 0 {
+		return 0, false
+	}
+	return float64(o.Engagements) * 100.0 / float64(denom), true
+}
+
+// rebaseRate returns a copy of o with the engagement rate recomputed on
+// basis, or o itself if basis is empty or its denominator is zero.
+func rebaseRate(o *model.OverviewData, basis string) *model.OverviewData {
+	if basis == "" {
+		return o
+	}
+	rate, ok := engagementRate(o, basis)
+	if !ok {
+		return o
+	}
+	rebased := *o
+	rebased.EngagementRate = rate
+	return &rebased
+}
+
+// rateTolerance is how many percentage points the engagement rate in the
+// overview CSV may differ from engagements / reach before it is flagged.
+// Publer rounds the rate to two decimals.
+const rateTolerance = 0.05
+
+// checkEngagementRate compares the engagement rate of the overview CSV to
+// the one computed from its reach and engagements and warns if they
+// disagree. It returns the computed rate, or false if reach is zero.
+func checkEngagementRate(overview *model.OverviewData) (float64, bool) {
+	rate, ok := engagementRate(overview, RateBasisReach)
+	if !ok {
+		return 0, false
+	}
+	if math.Abs(rate-overview.EngagementRate) > rateTolerance {
+		slog.Warn("engagement rate in the overview CSV differs from engagements / reach",
+			"workspace", overview.WorkspaceName,
+			"csv", fmt.Sprintf("%.2f%%", overview.EngagementRate),
+			"computed", fmt.Sprintf("%.2f%%", rate))
+	}
+	return rate, true
+}
+
+func prepareReportData(ctx context.Context, db *sql.DB, overview *model.OverviewData, posts []model.PostData, hashtags []model.HashtagData, periodName string, opts ReportOptions) *model.ReportData {
+	period := extractPeriodFromFilename(periodName)
+	month := extractMonthFromFilename(periodName)
+
+	checkEngagementRate(overview)
+	basis := opts.RateBasis
+	if basis == "" && opts.RecomputeRates {
+		basis = RateBasisReach
+	}
+	if _, ok := engagementRate(overview, basis); basis != "" && !ok {
+		slog.Warn("engagement rate kept from the overview CSV, because its denominator is zero", "basis", basis)
+		basis = ""
+	}
+	// Work on a copy so the comparisons below use the computed rate, too,
+	// while the caller's data keeps the CSV value.
+	overview = rebaseRate(overview, basis)
+
+	data := &model.ReportData{
+		Workspace:      cleanWorkspaceName(overview.WorkspaceName),
+		Month:          month,
+		Period:         period,
+		Followers:      overview.Followers,
+		Reach:          overview.Reach,
+		ReachRate:      overview.ReachRate,
+		Engagements:    overview.Engagements,
+		EngagementRate: overview.EngagementRate,
+		TopCountries:   overview.TopCountries,
+		Accounts:       overview.Accounts,
+		RankBy:         cmp.Or(opts.RankBy, DefaultRankBy),
+		HashtagRankBy:  cmp.Or(opts.HashtagRankBy, DefaultHashtagRankBy),
+		RateBasis:      basis,
+		Decimals:       opts.Decimals,
+		Language:       opts.Language,
+	}
+	if opts.Network != "" {
+		data.Network = platformName(opts.Network)
+	}
+
+	sort.Slice(data.TopCountries, func(i, j int) bool { return data.TopCountries[i].Users > data.TopCountries[j].Users })
+	data.TopCountries = topN(data.TopCountries, opts.Top)
+
+	posts = filterPostsByNetwork(posts, opts.Network)
+	currPeriod, err := extractDateFromFilename(periodName)
+	data.PeriodKey = currPeriod
+	data.Derived = deriveMetrics(overview, posts, currPeriod)
+	data.BestTimes = bestPostingTimes(posts, bestTimesSlots)
+
+	posts = filterPostsByType(posts, opts.PostTypes)
+	rankPosts(posts, data.RankBy)
+	data.TopPosts = topN(posts, opts.Top)
+	if opts.Redact {
+		data.TopPosts = redactPosts(data.TopPosts)
+	}
+
+	rankHashtags(hashtags, data.HashtagRankBy)
+	data.TopHashtags = topN(hashtags, opts.Top)
+
+	if err == nil {
+		if c := compareOverview(ctx, db, overview, currPeriod, -1, basis); c != nil {
+			slog.Info("comparing with previous period", "period", c.Period)
+			data.HasPrevious = true
+			data.PreviousPeriod = c.Period
+			data.FollowersChange = c.FollowersChange
+			data.FollowersChangePct = c.FollowersChangePct
+			data.ReachChange = c.ReachChange
+			data.ReachRateChange = c.ReachRateChange
+			data.EngagementsChange = c.EngagementsChange
+			data.EngagementRateChange = c.EngagementRateChange
+			if changes, herr := store.GetHashtagChanges(ctx, db, overview.WorkspaceName, currPeriod, c.Period); herr == nil {
+				data.HashtagRisers, data.HashtagFallers = hashtagMovers(changes, opts.Top)
+			} else {
+				slog.Warn("could not compare hashtags with previous period", "err", herr)
+			}
+		} else {
+			slog.Info("no previous period found", "period", currPeriod)
+		}
+		if opts.YearOverYear {
+			data.YearOverYear = compareOverview(ctx, db, overview, currPeriod, -12, basis)
+		}
+		if opts.History > 0 {
+			if history, herr := store.GetOverviewHistory(ctx, db, overview.WorkspaceName, currPeriod, opts.History); herr == nil {
+				for i := range history {
+					history[i] = *rebaseRate(&history[i], basis)
+				}
+				data.History = history
+			}
+		}
+	}
+
+	return data
+}
+
+// truncate collapses whitespace in s and shortens it to at most length
+// runes, so multi-byte characters are never split.
+func truncate(s string, length int) string {
+	clean := strings.Join(strings.Fields(s), " ")
+	r := []rune(clean)
+	if len(r) <= length {
+		return clean
+	}
+	return strings.TrimRight(string(r[:length]), " ") + "..."
+}
+
+// truncateWords is like truncate but ends the snippet on a whole word when
+// there is whitespace within the limit.
+func truncateWords(s string, length int) string {
+	clean := strings.Join(strings.Fields(s), " ")
+	r := []rune(clean)
+	if len(r) <= length {
+		return clean
+	}
+	cut := string(r[:length])
+	if r[length] != ' ' {
+		if i := strings.LastIndexByte(cut, ' '); i > 0 {
+			cut = cut[:i]
+		}
+	}
+	return strings.TrimRight(cut, " ") + "..."
+}
+
+// mdLink renders text as a Markdown link to url, or as plain text if url is
+// empty. Brackets in the text and parentheses and spaces in the URL are
+// escaped so they cannot end the link early.
+func mdLink(text, url string) string {
+	if url == "" {
+		return text
+	}
+	text = strings.NewReplacer("[", `\[`, "]", `\]`).Replace(text)
+	url = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(url)
+	return "[" + text + "](" + url + ")"
+}
+
+var platformNames = map[string]string{
+	"linkedin":  "LinkedIn",
+	"facebook":  "Facebook",
+	"instagram": "Instagram",
+	"twitter":   "Twitter",
+	"x":         "X",
+	"tiktok":    "TikTok",
+	"youtube":   "YouTube",
+	"pinterest": "Pinterest",
+	"threads":   "Threads",
+	"mastodon":  "Mastodon",
+	"bluesky":   "Bluesky",
+}
+
+// platformName returns the usual spelling of a social network name as
+// exported by Publer, such as "Linkedin".
+func platformName(network string) string {
+	network = strings.TrimSpace(network)
+	if name, ok := platformNames[strings.ToLower(network)]; ok {
+		return name
+	}
+	return network
+}
+
+func reportFuncMap() map[string]any {
+	funcs := map[string]any{
+		"add":           func(a, b int) int { return a + b },
+		"truncate":      truncate,
+		"truncateWords": truncateWords,
+		"mdLink":        mdLink,
+		"platform":      platformName,
+		"rankTitle":     func(by string) string { return lookupRankMetric(by).title },
+		"rankUnit":      func(by string) string { return lookupRankMetric(by).unit },
+		"rankValue":     func(by string, p model.PostData) int { return lookupRankMetric(by).value(p) },
+		"hashtagRankTitle": func(by string) string {
+			if m, ok := hashtagRankMetrics[by]; ok {
+				return m.title
+			}
+			return hashtagRankMetrics[DefaultHashtagRankBy].title
+		},
+		"hashtagEngagement": hashtagEngagement,
+		"periodMonth":       periodMonth,
+		"hourRange": func(h int) string {
+			return fmt.Sprintf("%02d:00–%02d:00", h, (h+1)%24)
+		},
+		"followersChange": func(n int) string {
+			switch {
+			case n > 0:
+				return "+" + groupThousands(strconv.Itoa(n)) + " new followers"
+			case n < 0:
+				return "-" + groupThousands(strconv.Itoa(-n)) + " lost followers"
+			}
+			return "no change in followers"
+		},
+	}
+	maps.Copy(funcs, numberFuncs(DefaultDecimals))
+	return funcs
+}
+
+// numberFuncs returns the template functions that format numbers with the
+// given number of decimal places: pct for percentages, num for counts and
+// averages, and percentChange for the changes since another period. Counts
+// get thousands separators.
+func numberFuncs(decimals int) map[string]any {
+	decimals = max(decimals, 0)
+	return map[string]any{
+		"pct": func(f float64) string { return formatDecimal(f, decimals) + "%" },
+		"num": func(v any) string {
+			switch n := v.(type) {
+			case int:
+				return groupThousands(strconv.Itoa(n))
+			case float64:
+				return formatDecimal(n, decimals)
+			}
+			return fmt.Sprint(v)
+		},
+		"percentChange": func(f float64) string {
+			pct := formatDecimal(math.Abs(f), decimals)
+			switch {
+			case strings.Trim(pct, "0.,") == "":
+				return "no change"
+			case f > 0:
+				return "+" + pct + "% increase"
+			}
+			return "-" + pct + "% decrease"
+		},
+	}
+}
+
+func formatDecimal(f float64, decimals int) string {
+	return groupThousands(strconv.FormatFloat(f, 'f', decimals, 64))
+}
+
+// groupThousands inserts a comma between each group of three digits of the
+// integer part of the formatted number s.
+func groupThousands(s string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, frac, _ := strings.Cut(s, ".")
+	if frac != "" {
+		frac = "." + frac
+	}
+
+	var sb strings.Builder
+	for i, d := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(d)
+	}
+	return sign + sb.String() + frac
+}
+
+func generateReport(data *model.ReportData, filename, format string, mdTemplate *template.Template) error {
+	content, err := renderFormat(data, format, mdTemplate)
+	if err != nil {
+		return err
+	}
+
+	return writeReport(content, filename)
+}
+
+// renderFormat renders data in the given format. mdTemplate replaces the
+// built-in Markdown template if it is not nil.
+func renderFormat(data *model.ReportData, format string, mdTemplate *template.Template) (string, error) {
+	switch format {
+	case "html":
+		return renderHTMLReport(data)
+	case "json":
+		return renderJSONReport(data)
+	case "csv":
+		return renderCSVReport(data)
+	}
+	return renderReport(data, mdTemplate)
+}
+
+// writeReport writes content to a temporary file next to filename and
+// renames it over filename only once it is complete, so that a failed or
+// interrupted run never leaves a truncated report in place of a good one.
+func writeReport(content, filename string) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.WriteString(content); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(0o644); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+func renderReport(data *model.ReportData, custom *template.Template) (string, error) {
+	tmpl := `# {{.Month}} KPIs
+
+For {{.Workspace}}{{with .Network}} on {{.}}{{end}}
+
+For the period {{.Period}}
+{{with .AIWarning}}
+> **Warning:** {{.}}
+{{end}}
+## Monthly Performance Summary
+
+- Total Followers: {{num .Followers}}{{if .HasPrevious}} ({{followersChange .FollowersChange}}{{with .FollowersChangePct}}, {{percentChange .}}{{end}}){{end}}
+- Total Reach: {{num .Reach}}{{if .HasPrevious}} ({{percentChange .ReachChange}}){{end}}
+- Total Engagements: {{num .Engagements}}{{if .HasPrevious}} ({{percentChange .EngagementsChange}}){{end}}
+- Engagement Rate{{with .RateBasis}} (engagements / {{.}}){{end}}: {{pct .EngagementRate}}{{if .HasPrevious}} ({{percentChange .EngagementRateChange}}){{end}}
+
+## Efficiency Metrics
+
+- Reach Rate: {{pct .ReachRate}}{{if .HasPrevious}} ({{percentChange .ReachRateChange}}){{end}}
+
+## Derived Metrics
+{{with .Derived}}
+- Posts: {{num .Posts}} ({{num .PostsPerDay}} per day)
+- Average Reactions per Post: {{num .AvgReactions}}
+- Average Comments per Post: {{num .AvgComments}}
+- Average Shares per Post: {{num .AvgShares}}
+- Engagements per Day: {{num .EngagementsPerDay}}
+- Reach per Follower: {{printf "%.3f" .ReachPerFollower}}
+{{end}}{{with .Accounts}}
+## Per-Network Breakdown
+
+| Account | Network | Followers | Reach | Engagements | Engagement Rate |
+| --- | --- | ---: | ---: | ---: | ---: |
+{{range .}}| {{.Account}} | {{platform .Network}} | {{num .Followers}} | {{num .Reach}} | {{num .Engagements}} | {{pct .EngagementRate}} |
+{{end}}{{end}}{{with .YearOverYear}}
+## Year-over-Year Comparison
+
+Compared to {{.Month}}:
+
+- Followers: {{followersChange .FollowersChange}}{{with .FollowersChangePct}}, {{percentChange .}}{{end}}
+- Reach: {{percentChange .ReachChange}}
+- Reach Rate: {{percentChange .ReachRateChange}}
+- Engagements: {{percentChange .EngagementsChange}}
+- Engagement Rate: {{percentChange .EngagementRateChange}}
+{{end}}{{if gt (len .History) 1}}
+## Historical Trend
+
+| Period | Followers | Reach | Engagement Rate |
+| --- | ---: | ---: | ---: |
+{{range .History}}| {{.Period}} | {{num .Followers}} | {{num .Reach}} | {{pct .EngagementRate}} |
+{{end}}{{end}}{{with .BestTimes}}
+## Best Posting Times
+
+{{range $i, $slot := .}}
+{{add $i 1}}. {{$slot.Weekday}}, {{hourRange $slot.Hour}}: {{num $slot.AvgEngagements}} engagements and {{num $slot.AvgReactions}} reactions per post ({{num $slot.Posts}} {{if eq $slot.Posts 1}}post{{else}}posts{{end}})
+{{end}}{{end}}
+## Interaction Breakdown
+
+### Top-Performing Posts by {{rankTitle .RankBy}}
+
+{{range $i, $post := .TopPosts}}
+{{add $i 1}}. {{mdLink (truncateWords $post.PostText 50) $post.PostLink}}{{with platform $post.SocialNetwork}} — {{.}}{{end}} ({{num (rankValue $.RankBy $post)}} {{rankUnit $.RankBy}}{{if $post.EngagementRate}}, {{pct $post.EngagementRate}} engagement rate{{end}})
+{{else}}
+No data available for this period.
+{{end}}
+
+### Top Hashtags by {{hashtagRankTitle .HashtagRankBy}}
+
+{{range $i, $hashtag := .TopHashtags}}
+{{add $i 1}}. {{$hashtag.Hashtag}} (score {{printf "%.2f" $hashtag.Score}}, reach {{num $hashtag.Reach}}, {{num (hashtagEngagement $hashtag)}} engagements)
+{{else}}
+No data available for this period.
+{{end}}
+{{if or .HashtagRisers .HashtagFallers}}
+### Hashtag Movers since {{periodMonth .PreviousPeriod}}
+{{with .HashtagRisers}}
+Rising:
+{{range $i, $c := .}}
+{{add $i 1}}. {{$c.Hashtag}} (score {{printf "%.2f" $c.Score}}, {{printf "%+.2f" $c.Change}}{{if $c.New}}, new{{end}})
+{{end}}{{end}}{{with .HashtagFallers}}
+Falling:
+{{range $i, $c := .}}
+{{add $i 1}}. {{$c.Hashtag}} (score {{printf "%.2f" $c.Score}}, {{printf "%+.2f" $c.Change}}{{if $c.Dropped}}, not used this month{{end}})
+{{end}}{{end}}{{end}}
+### Geographic Distribution
+
+{{range $i, $country := .TopCountries}}
+{{add $i 1}}. {{$country.Country}} ({{pct $country.Percentage}})
+{{else}}
+No data available for this period.
+{{end}}
+
+## Insights and Recommendations
+
+{{.Insights}}
+
+## Next Steps
+
+{{.NextSteps}}
+`
+
+	var t *template.Template
+	var err error
+	if custom != nil {
+		// Clone before binding the number functions, because the template
+		// is shared by all reports of a run.
+		if t, err = custom.Clone(); err != nil {
+			return "", err
+		}
+		t.Funcs(numberFuncs(data.Decimals))
+	} else if t, err = template.New("report").Funcs(reportFuncMap()).Funcs(numberFuncs(data.Decimals)).Parse(tmpl); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// loadReportTemplate parses a custom Markdown report template with the
+// functions of the built-in one. It runs the template once on empty data, so
+// that a misspelled field fails at startup instead of after the CSV files
+// have been processed.
+func loadReportTemplate(path string) (*template.Template, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading report template: %w", err)
+	}
+
+	t, err := template.New(filepath.Base(path)).Funcs(reportFuncMap()).Parse(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing report template: %w", err)
+	}
+
+	if err := t.Execute(io.Discard, &model.ReportData{}); err != nil {
+		return nil, fmt.Errorf("error in report template: %w", err)
+	}
+
+	return t, nil
+}
+
+func renderHTMLReport(data *model.ReportData) (string, error) {
+	tmpl := `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Month}} KPIs</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 50em; margin: 2em auto; padding: 0 1em; color: #222; line-height: 1.5; }
+h1, h2, h3 { line-height: 1.2; }
+table { border-collapse: collapse; width: 100%; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 0.4em 0.6em; text-align: left; }
+th { background: #f4f4f4; }
+td.num { text-align: right; }
+.ai { white-space: pre-wrap; }
+.warning { border: 1px solid #e0b000; background: #fff6d5; padding: 0.6em 0.8em; }
+</style>
+</head>
+<body>
+<h1>{{.Month}} KPIs</h1>
+<p>For {{.Workspace}}{{with .Network}} on {{.}}{{end}}</p>
+
+<p>For the period {{.Period}}</p>
+{{with .AIWarning}}
+<p class="warning"><strong>Warning:</strong> {{.}}</p>
+{{end}}
+<h2>Monthly Performance Summary</h2>
+
+<ul>
+<li>Total Followers: {{num .Followers}}{{if .HasPrevious}} ({{followersChange .FollowersChange}}{{with .FollowersChangePct}}, {{percentChange .}}{{end}}){{end}}</li>
+<li>Total Reach: {{num .Reach}}{{if .HasPrevious}} ({{percentChange .ReachChange}}){{end}}</li>
+<li>Total Engagements: {{num .Engagements}}{{if .HasPrevious}} ({{percentChange .EngagementsChange}}){{end}}</li>
+<li>Engagement Rate{{with .RateBasis}} (engagements / {{.}}){{end}}: {{pct .EngagementRate}}{{if .HasPrevious}} ({{percentChange .EngagementRateChange}}){{end}}</li>
+</ul>
+
+<h2>Efficiency Metrics</h2>
+
+<ul>
+<li>Reach Rate: {{pct .ReachRate}}{{if .HasPrevious}} ({{percentChange .ReachRateChange}}){{end}}</li>
+</ul>
+
+<h2>Derived Metrics</h2>
+{{with .Derived}}
+<ul>
+<li>Posts: {{num .Posts}} ({{num .PostsPerDay}} per day)</li>
+<li>Average Reactions per Post: {{num .AvgReactions}}</li>
+<li>Average Comments per Post: {{num .AvgComments}}</li>
+<li>Average Shares per Post: {{num .AvgShares}}</li>
+<li>Engagements per Day: {{num .EngagementsPerDay}}</li>
+<li>Reach per Follower: {{printf "%.3f" .ReachPerFollower}}</li>
+</ul>
+{{end}}{{with .Accounts}}
+<h2>Per-Network Breakdown</h2>
+
+<table>
+<tr><th>Account</th><th>Network</th><th>Followers</th><th>Reach</th><th>Engagements</th><th>Engagement Rate</th></tr>
+{{range .}}<tr><td>{{.Account}}</td><td>{{platform .Network}}</td><td class="num">{{num .Followers}}</td><td class="num">{{num .Reach}}</td><td class="num">{{num .Engagements}}</td><td class="num">{{pct .EngagementRate}}</td></tr>
+{{end}}</table>
+{{end}}{{with .YearOverYear}}
+<h2>Year-over-Year Comparison</h2>
+
+<p>Compared to {{.Month}}:</p>
+
+<ul>
+<li>Followers: {{followersChange .FollowersChange}}{{with .FollowersChangePct}}, {{percentChange .}}{{end}}</li>
+<li>Reach: {{percentChange .ReachChange}}</li>
+<li>Reach Rate: {{percentChange .ReachRateChange}}</li>
+<li>Engagements: {{percentChange .EngagementsChange}}</li>
+<li>Engagement Rate: {{percentChange .EngagementRateChange}}</li>
+</ul>
+{{end}}{{if gt (len .History) 1}}
+<h2>Historical Trend</h2>
+
+<table>
+<tr><th>Period</th><th>Followers</th><th>Reach</th><th>Engagement Rate</th></tr>
+{{range .History}}<tr><td>{{.Period}}</td><td class="num">{{num .Followers}}</td><td class="num">{{num .Reach}}</td><td class="num">{{pct .EngagementRate}}</td></tr>
+{{end}}</table>
+{{end}}{{with .BestTimes}}
+<h2>Best Posting Times</h2>
+
+<table>
+<tr><th>#</th><th>Weekday</th><th>Time</th><th>Engagements per Post</th><th>Reactions per Post</th><th>Posts</th></tr>
+{{range $i, $slot := .}}<tr><td class="num">{{add $i 1}}</td><td>{{$slot.Weekday}}</td><td>{{hourRange $slot.Hour}}</td><td class="num">{{num $slot.AvgEngagements}}</td><td class="num">{{num $slot.AvgReactions}}</td><td class="num">{{num $slot.Posts}}</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Interaction Breakdown</h2>
+
+<h3>Top-Performing Posts by {{rankTitle .RankBy}}</h3>
+
+{{if .TopPosts}}<table>
+<tr><th>#</th><th>Post</th><th>Platform</th><th>{{rankTitle .RankBy}}</th><th>Engagement Rate</th></tr>
+{{range $i, $post := .TopPosts}}<tr><td class="num">{{add $i 1}}</td><td>{{if $post.PostLink}}<a href="{{$post.PostLink}}">{{truncateWords $post.PostText 50}}</a>{{else}}{{truncateWords $post.PostText 50}}{{end}}</td><td>{{platform $post.SocialNetwork}}</td><td class="num">{{num (rankValue $.RankBy $post)}}</td><td class="num">{{if $post.EngagementRate}}{{pct $post.EngagementRate}}{{else}}-{{end}}</td></tr>
+{{end}}</table>
+{{else}}<p>No data available for this period.</p>
+{{end}}
+
+<h3>Top Hashtags by {{hashtagRankTitle .HashtagRankBy}}</h3>
+
+{{if .TopHashtags}}<table>
+<tr><th>#</th><th>Hashtag</th><th>Score</th><th>Reach</th><th>Engagement</th></tr>
+{{range $i, $hashtag := .TopHashtags}}<tr><td class="num">{{add $i 1}}</td><td>{{$hashtag.Hashtag}}</td><td class="num">{{printf "%.2f" $hashtag.Score}}</td><td class="num">{{num $hashtag.Reach}}</td><td class="num">{{num (hashtagEngagement $hashtag)}}</td></tr>
+{{end}}</table>
+{{else}}<p>No data available for this period.</p>
+{{end}}
+
+{{if or .HashtagRisers .HashtagFallers}}<h3>Hashtag Movers since {{periodMonth .PreviousPeriod}}</h3>
+
+<table>
+<tr><th>Hashtag</th><th>Score</th><th>Previous Score</th><th>Change</th></tr>
+{{range .HashtagRisers}}<tr><td>{{.Hashtag}}{{if .New}} (new){{end}}</td><td class="num">{{printf "%.2f" .Score}}</td><td class="num">{{printf "%.2f" .PreviousScore}}</td><td class="num">{{printf "%+.2f" .Change}}</td></tr>
+{{end}}{{range .HashtagFallers}}<tr><td>{{.Hashtag}}{{if .Dropped}} (not used this month){{end}}</td><td class="num">{{printf "%.2f" .Score}}</td><td class="num">{{printf "%.2f" .PreviousScore}}</td><td class="num">{{printf "%+.2f" .Change}}</td></tr>
+{{end}}</table>
+
+{{end}}<h3>Geographic Distribution</h3>
+
+{{if .TopCountries}}<table>
+<tr><th>#</th><th>Country</th><th>Share</th></tr>
+{{range $i, $country := .TopCountries}}<tr><td class="num">{{add $i 1}}</td><td>{{$country.Country}}</td><td class="num">{{pct $country.Percentage}}</td></tr>
+{{end}}</table>
+{{else}}<p>No data available for this period.</p>
+{{end}}
+
+<h2>Insights and Recommendations</h2>
+
+<div class="ai">{{.Insights}}</div>
+
+<h2>Next Steps</h2>
+
+<div class="ai">{{.NextSteps}}</div>
+</body>
+</html>
+`
+
+	t, err := htmltemplate.New("report").Funcs(reportFuncMap()).Funcs(numberFuncs(data.Decimals)).Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func renderJSONReport(data any) (string, error) {
+	b, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error marshaling report data: %w", err)
+	}
+	return string(b) + "\n", nil
+}
+
+// CSV export section headers. The first column names the section on every
+// row so scripts can filter them; keep the columns stable.
+var (
+	csvOverviewHeader = []string{"section", "metric", "value", "change"}
+	csvPostHeader     = []string{"section", "rank", "date", "social_network", "post_type", "post_link", "post_text", "reach", "reactions", "comments", "shares", "engagement_rate", "link_clicks"}
+	csvHashtagHeader  = []string{"section", "rank", "hashtag", "score", "reach", "reactions", "comments", "shares"}
+	csvCountryHeader  = []string{"section", "rank", "country", "users", "percentage"}
+	csvAccountHeader  = []string{"section", "account", "network", "followers", "reach", "engagements", "engagement_rate"}
+)
+
+func renderCSVReport(data *model.ReportData) (string, error) {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+
+	num := func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+	change := func(f float64) string {
+		if !data.HasPrevious {
+			return ""
+		}
+		return num(f)
+	}
+
+	w.Write(csvOverviewHeader)
+	w.Write([]string{"overview", "period", data.Period, ""})
+	w.Write([]string{"overview", "followers", strconv.Itoa(data.Followers), change(float64(data.FollowersChange))})
+	if p := data.FollowersChangePct; p != nil {
+		w.Write([]string{"overview", "followers_change_pct", "", num(*p)})
+	}
+	w.Write([]string{"overview", "reach", strconv.Itoa(data.Reach), change(data.ReachChange)})
+	w.Write([]string{"overview", "reach_rate", num(data.ReachRate), change(data.ReachRateChange)})
+	w.Write([]string{"overview", "engagements", strconv.Itoa(data.Engagements), change(data.EngagementsChange)})
+	w.Write([]string{"overview", "engagement_rate", num(data.EngagementRate), change(data.EngagementRateChange)})
+	if data.RateBasis != "" {
+		w.Write([]string{"overview", "engagement_rate_basis", data.RateBasis, ""})
+	}
+
+	w.Write(nil)
+	w.Write(csvPostHeader)
+	for i, p := range data.TopPosts {
+		w.Write([]string{"post", strconv.Itoa(i + 1), p.Date, p.SocialNetwork, p.PostType, p.PostLink, p.PostText,
+			strconv.Itoa(p.Reach), strconv.Itoa(p.Reactions), strconv.Itoa(p.Comments), strconv.Itoa(p.Shares), num(p.EngagementRate), strconv.Itoa(p.LinkClicks)})
+	}
+
+	w.Write(nil)
+	w.Write(csvHashtagHeader)
+	for i, h := range data.TopHashtags {
+		w.Write([]string{"hashtag", strconv.Itoa(i + 1), h.Hashtag, num(h.Score), strconv.Itoa(h.Reach), strconv.Itoa(h.Reactions), strconv.Itoa(h.Comments), strconv.Itoa(h.Shares)})
+	}
+
+	w.Write(nil)
+	w.Write(csvCountryHeader)
+	for i, c := range data.TopCountries {
+		w.Write([]string{"country", strconv.Itoa(i + 1), c.Country, strconv.Itoa(c.Users), num(c.Percentage)})
+	}
+
+	if len(data.Accounts) > 0 {
+		w.Write(nil)
+		w.Write(csvAccountHeader)
+		for _, a := range data.Accounts {
+			w.Write([]string{"account", a.Account, a.Network, strconv.Itoa(a.Followers), strconv.Itoa(a.Reach), strconv.Itoa(a.Engagements), num(a.EngagementRate)})
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", fmt.Errorf("error writing CSV: %w", err)
+	}
+	return sb.String(), nil
+}
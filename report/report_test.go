@@ -0,0 +1,185 @@
+package report
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/christophberger/publer-analytics-report/model"
+	"github.com/christophberger/publer-analytics-report/store"
+)
+
+func TestChangesNeedPrevious(t *testing.T) {
+	pct := 2.5
+	previous := &model.ReportData{
+		Workspace: "Acme", Month: "July 2025", Period: "1 Jul 2025 - 31 Jul 2025", Decimals: DefaultDecimals,
+		Followers: 4100, Reach: 507, Engagements: 105, EngagementRate: 20.71, ReachRate: 3.59,
+		HasPrevious: true, FollowersChange: 100, FollowersChangePct: &pct,
+		ReachChange: 12.5, EngagementsChange: -4, EngagementRateChange: 0, ReachRateChange: 1,
+	}
+	first := *previous
+	first.HasPrevious, first.FollowersChange, first.FollowersChangePct = false, 0, nil
+	first.ReachChange, first.EngagementsChange, first.ReachRateChange = 0, 0, 0
+
+	tests := []struct {
+		format string
+		data   *model.ReportData
+		want   []string
+		absent []string
+	}{
+		{"md", previous, []string{"Total Followers: 4,100 (+100 new followers, +2.5% increase)", "Total Reach: 507 (+12.5% increase)", "Total Engagements: 105 (-4.0% decrease)", "Engagement Rate: 20.7% (no change)", "Reach Rate: 3.6% (+1.0% increase)"}, nil},
+		{"md", &first, []string{"Total Followers: 4,100\n", "Total Reach: 507\n", "Engagement Rate: 20.7%\n", "Reach Rate: 3.6%\n"}, []string{"no change", "followers)"}},
+		{"html", previous, []string{"<li>Total Followers: 4,100 (&#43;100 new followers, &#43;2.5% increase)</li>", "<li>Total Engagements: 105 (-4.0% decrease)</li>", "Reach Rate: 3.6% (&#43;1.0% increase)</li>"}, nil},
+		{"html", &first, []string{"<li>Total Followers: 4,100</li>", "<li>Total Reach: 507</li>", "Engagement Rate: 20.7%</li>", "Reach Rate: 3.6%</li>"}, []string{"no change", "followers)"}},
+		{"csv", previous, []string{"overview,followers,4100,100\n", "overview,followers_change_pct,,2.5\n"}, nil},
+		{"csv", &first, []string{"overview,followers,4100,\n"}, []string{"followers_change_pct"}},
+	}
+	for _, tt := range tests {
+		out, err := renderFormat(tt.data, tt.format, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s report with HasPrevious %v lacks %q", tt.format, tt.data.HasPrevious, want)
+			}
+		}
+		for _, absent := range tt.absent {
+			if strings.Contains(out, absent) {
+				t.Errorf("%s report with HasPrevious %v contains %q", tt.format, tt.data.HasPrevious, absent)
+			}
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s      string
+		length int
+		want   string
+	}{
+		{"Hello", 10, "Hello"},
+		{"Hello", 5, "Hello"},
+		{"Hello world", 5, "Hello..."},
+		{"Hello world", 6, "Hello..."},
+		{"  Hello\n  world  ", 20, "Hello world"},
+		// Byte 3 is inside the emoji, rune 3 after it.
+		{"ab👋cd", 3, "ab👋..."},
+		{"ab👋cd", 2, "ab..."},
+		{"👋👋👋", 2, "👋👋..."},
+		{"Grüße aus Zürich", 4, "Grüß..."},
+		{"", 5, ""},
+	}
+	for _, tt := range tests {
+		got := truncate(tt.s, tt.length)
+		if got != tt.want || !utf8.ValidString(got) {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.length, got, tt.want)
+		}
+	}
+}
+
+func TestTopPostEngagementRate(t *testing.T) {
+	data := &model.ReportData{
+		Workspace: "Acme", Month: "July 2025", Decimals: DefaultDecimals, RankBy: DefaultRankBy,
+		TopPosts: []model.PostData{
+			{PostText: "Sign up", PostLink: "https://example.com/1", SocialNetwork: "Linkedin", Reactions: 12, EngagementRate: 5.88},
+			{PostText: "No rate", SocialNetwork: "Linkedin", Reactions: 3},
+		},
+	}
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"md", []string{"1. [Sign up](https://example.com/1) — LinkedIn (12 reactions, 5.9% engagement rate)\n", "2. No rate — LinkedIn (3 reactions)\n"}},
+		{"html", []string{`<td class="num">12</td><td class="num">5.9%</td>`, `<td class="num">3</td><td class="num">-</td>`}},
+	}
+	for _, tt := range tests {
+		out, err := renderFormat(data, tt.format, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s report lacks %q", tt.format, want)
+			}
+		}
+	}
+}
+
+func TestPeriodOffset(t *testing.T) {
+	// The period math must follow the period alone, not the clock.
+	defer func(old func() time.Time) { now = old }(now)
+	now = func() time.Time { return time.Date(2031, time.June, 15, 12, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		period string
+		months int
+		want   string
+	}{
+		{"2026-01", -1, "2025-12"},
+		{"2025-12", 1, "2026-01"},
+		{"2025-12", -1, "2025-11"},
+		{"2026-01", -12, "2025-01"},
+		{"2025-12", -12, "2024-12"},
+		{"2024-03", -1, "2024-02"},
+	}
+	for _, tt := range tests {
+		if got, err := periodOffset(tt.period, tt.months); got != tt.want || err != nil {
+			t.Errorf("periodOffset(%q, %d) = %q, %v, want %q", tt.period, tt.months, got, err, tt.want)
+		}
+	}
+}
+
+// TestComparisonsAcrossYears checks the previous month and the year over
+// year comparisons of reports whose compared months lie in another year.
+func TestComparisonsAcrossYears(t *testing.T) {
+	defer func(old func() time.Time) { now = old }(now)
+
+	ctx := context.Background()
+	db, err := store.Open(filepath.Join(t.TempDir(), "analytics.db"), store.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := store.InitSchema(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []struct {
+		period    string
+		followers int
+	}{{"2024-12", 900}, {"2025-01", 1000}, {"2025-11", 1100}, {"2025-12", 1200}} {
+		overview := &model.OverviewData{WorkspaceName: "Acme", Followers: p.followers}
+		if _, err := store.SavePeriod(ctx, db, p.period, overview, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name              string
+		now               time.Time
+		file              string
+		followers         int
+		previous, yearAgo string
+		followersChange   int
+		yearAgoChange     int
+	}{
+		{"January after December", time.Date(2026, time.February, 2, 9, 0, 0, 0, time.UTC),
+			"Acme ∙ Overview ∙ 1 Jan 2026 - 31 Jan 2026.csv", 1300, "2025-12", "2025-01", 100, 300},
+		{"December in January", time.Date(2026, time.January, 1, 0, 30, 0, 0, time.UTC),
+			"Acme ∙ Overview ∙ 1 Dec 2025 - 31 Dec 2025.csv", 1200, "2025-11", "2024-12", 100, 300},
+	}
+	for _, tt := range tests {
+		now = func() time.Time { return tt.now }
+		overview := &model.OverviewData{WorkspaceName: "Acme", Followers: tt.followers}
+		data := prepareReportData(ctx, db, overview, nil, nil, tt.file, ReportOptions{YearOverYear: true})
+		if !data.HasPrevious || data.PreviousPeriod != tt.previous || data.FollowersChange != tt.followersChange {
+			t.Errorf("%s: previous period %q (found %v), followers change %d, want %s, %d", tt.name, data.PreviousPeriod, data.HasPrevious, data.FollowersChange, tt.previous, tt.followersChange)
+		}
+		if yoy := data.YearOverYear; yoy == nil || yoy.Period != tt.yearAgo || yoy.FollowersChange != tt.yearAgoChange {
+			t.Errorf("%s: year over year %+v, want period %s, followers change %d", tt.name, yoy, tt.yearAgo, tt.yearAgoChange)
+		}
+	}
+}
@@ -0,0 +1,1003 @@
+// Package report runs the Publer Analytics report pipeline: it reads the
+// CSV exports, stores the period in the database, asks the AI for insights,
+// and renders the report files. The command in the module root is a thin
+// flag parser on top of Run.
+package report
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/christophberger/publer-analytics-report/insights"
+	"github.com/christophberger/publer-analytics-report/model"
+	"github.com/christophberger/publer-analytics-report/parser"
+	"github.com/christophberger/publer-analytics-report/store"
+)
+
+// Options configures a report run. The zero value of a field selects the
+// same default as the command-line flag of the same name.
+type Options struct {
+	// Input is the CSV file, directory, or ZIP archive to report on. With
+	// Recursive set, it is a parent directory with one subdirectory per
+	// workspace.
+	Input     string
+	Recursive bool
+	// StdinType, if set, reads the export of this type, overview, posts, or
+	// hashtags, from Stdin instead of finding the files of Input. The other
+	// exports are read from OverviewFile, PostsFile, and HashtagsFile. The
+	// overview is required, while a missing posts or hashtags export skips
+	// its sections and keeps the period's stored rows.
+	StdinType    string
+	Stdin        io.Reader
+	OverviewFile string
+	PostsFile    string
+	HashtagsFile string
+	// Period is the month of a StdinType run as YYYY-MM. Empty takes it
+	// from the name of the first export file.
+	Period string
+	// Output is the report file or directory. Empty writes the generated
+	// filename to the current directory.
+	Output string
+	// Formats lists the report formats to write: md, html, json, or csv.
+	// Empty writes Markdown only.
+	Formats []string
+	// TemplateFile replaces the built-in Markdown template if set.
+	TemplateFile string
+	// Workspace, if set, replaces the workspace name from the overview
+	// file, which is also the key that periods are stored and compared by.
+	Workspace string
+	// ConfigFile is the configuration file. Empty searches the default
+	// locations.
+	ConfigFile string
+	// DBPath is the SQLite database. Empty uses $PUBLER_DB or analytics.db.
+	DBPath    string
+	Delimiter rune
+	Latest    bool
+	Force     bool
+	NoAI      bool
+	// FailOnAIError makes a failed AI call fail the report instead of
+	// adding a warning banner to it.
+	FailOnAIError bool
+	// Quiet suppresses the success and summary lines.
+	Quiet bool
+	// IngestOnly stores the periods of the CSV files in the database
+	// without writing reports or calling the AI, as the ingest subcommand
+	// does. It implies NoAI.
+	IngestOnly bool
+	// Append writes the Markdown report as a section of the workspace's
+	// master report instead of a file of its own.
+	Append bool
+	// Open opens the first report file of each workspace once it is
+	// written, in $EDITOR or with the platform's default handler. It is
+	// ignored with Quiet.
+	Open bool
+	// Manifest writes a <report>.manifest.json file describing the inputs
+	// and outputs of each report.
+	Manifest bool
+	// Stream requests the AI texts as server-sent events and, unless Quiet
+	// is set, echoes them to stderr as they arrive.
+	Stream bool
+	// Strict fails a report if a numeric cell of the CSV files does not
+	// hold a number, instead of reading it as zero with a warning.
+	Strict bool
+	// StrictCSV fails a report if a data row of the CSV files does not have
+	// as many fields as the header row, instead of skipping the rows that
+	// cannot be read with a warning.
+	StrictCSV bool
+	// RedactDB stores the posts with their text replaced by "[redacted]".
+	// It implies Report.Redact.
+	RedactDB bool
+	// NoCache sends every prompt to the API instead of reusing the stored
+	// response to an identical prompt with the same model and settings.
+	NoCache bool
+	// MetricsFile, if set, receives the key numbers of each report in the
+	// Prometheus text format, for monitoring scheduled runs.
+	MetricsFile string
+	// DumpPrompts, if set, receives the rendered AI prompts of each report
+	// before they are sent, even with NoAI set.
+	DumpPrompts io.Writer
+	Report      ReportOptions
+	// ExplicitTop keeps Report.Top even if the config sets top.
+	ExplicitTop bool
+	// Generator replaces the API client from the configuration file if
+	// set, for example with a fake that returns canned text.
+	Generator insights.Generator
+}
+
+const aiSkippedText = "(AI generation skipped)"
+
+// ErrAIFailed marks a run that wrote its reports with placeholder text
+// because the AI insights or next steps could not be generated.
+var ErrAIFailed = errors.New("AI generation failed")
+
+// Run runs the report pipeline described by opts.
+func Run(opts Options) error {
+	return RunContext(context.Background(), opts)
+}
+
+// RunContext is like Run but stops when ctx is canceled.
+func RunContext(ctx context.Context, opts Options) error {
+	if opts.Input == "" && opts.StdinType == "" {
+		return errors.New("no input file or directory given")
+	}
+	if opts.Workspace != "" && opts.Recursive {
+		return errors.New("a workspace name cannot be combined with a recursive run")
+	}
+	if opts.StdinType != "" && (opts.Input != "" || opts.Recursive) {
+		return errors.New("input from stdin cannot be combined with an input file or directory")
+	}
+
+	ro, err := newRunOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	dbPath, err := DefaultDBPath(opts.DBPath)
+	if err != nil {
+		return fmt.Errorf("error resolving database path: %w", err)
+	}
+	ro.dbPath = dbPath
+
+	db, err := store.Open(dbPath, ro.storeOptions())
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer db.Close()
+
+	if err := store.InitSchema(ctx, db); err != nil {
+		return fmt.Errorf("error initializing database: %w", err)
+	}
+	ro.useCache(db)
+
+	err = runReports(ctx, db, opts, ro)
+	if opts.MetricsFile != "" {
+		// Reports that failed are missing from the metrics, which is what
+		// an alert on them should notice.
+		if merr := writeMetrics(ro.metrics, opts.MetricsFile); merr != nil {
+			if err != nil {
+				slog.Error("could not write metrics file", "err", merr)
+				return err
+			}
+			return fmt.Errorf("error writing metrics file: %w", merr)
+		}
+	}
+	return err
+}
+
+// runReports writes the reports of the run described by opts.
+func runReports(ctx context.Context, db *sql.DB, opts Options, ro *runOptions) error {
+	if opts.StdinType != "" {
+		overview, posts, hashtags, period, err := stdinFiles(opts)
+		if err != nil {
+			return err
+		}
+		ro.stdin = opts.Stdin
+		ro.period = period
+		return processFiles(ctx, db, stdinName, overview, posts, hashtags, ro)
+	}
+	if !opts.Recursive {
+		return processWorkspace(ctx, db, opts.Input, ro)
+	}
+
+	dirs, err := workspaceDirs(opts.Input)
+	if err != nil {
+		return fmt.Errorf("error listing workspace directories: %w", err)
+	}
+	if len(dirs) == 0 {
+		return fmt.Errorf("no subdirectories with CSV files found in %s", opts.Input)
+	}
+
+	// Each workspace gets its own report file, so a single output file
+	// would be overwritten; treat the output as a directory instead.
+	if ro.output != "" && !os.IsPathSeparator(ro.output[len(ro.output)-1]) {
+		ro.output += string(filepath.Separator)
+	}
+
+	failed, aiFailed := 0, 0
+	for _, dir := range dirs {
+		if ctx.Err() != nil {
+			return fmt.Errorf("interrupted: %w", ctx.Err())
+		}
+		wo, err := ro.forWorkspace(db, dir)
+		if err == nil {
+			err = processWorkspace(ctx, db, dir, wo)
+			// The workspace's options append to a copy of the metrics.
+			ro.metrics = wo.metrics
+		}
+		switch {
+		case errors.Is(err, ErrAIFailed):
+			aiFailed++
+		case err != nil:
+			slog.Error("workspace failed", "dir", dir, "err", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d workspaces failed", failed, len(dirs))
+	}
+	if aiFailed > 0 {
+		return fmt.Errorf("%d of %d workspaces: %w", aiFailed, len(dirs), ErrAIFailed)
+	}
+	return nil
+}
+
+// newRunOptions loads the configuration, prompts, and template that opts
+// refer to.
+func newRunOptions(opts Options) (*runOptions, error) {
+	formats := opts.Formats
+	if len(formats) == 0 {
+		formats = []string{"md"}
+	}
+
+	if opts.RedactDB {
+		opts.Report.Redact = true
+	}
+	if opts.IngestOnly {
+		opts.NoAI = true
+	}
+
+	ro := &runOptions{
+		output:        opts.Output,
+		formats:       formats,
+		workspace:     opts.Workspace,
+		latest:        opts.Latest,
+		force:         opts.Force,
+		noAI:          opts.NoAI,
+		failOnAIError: opts.FailOnAIError,
+		quiet:         opts.Quiet,
+		ingestOnly:    opts.IngestOnly,
+		open:          opts.Open && !opts.Quiet,
+		appendMD:      opts.Append,
+		manifest:      opts.Manifest,
+		dumpPrompts:   opts.DumpPrompts,
+		cache:         !opts.NoCache,
+		strict:        opts.Strict,
+		stream:        opts.Stream,
+		redactDB:      opts.RedactDB,
+		parse:         parser.Options{Delimiter: opts.Delimiter, StrictRows: opts.StrictCSV},
+		report:        opts.Report,
+		reportFlags:   opts.Report,
+		explicitTop:   opts.ExplicitTop,
+		generator:     opts.Generator,
+	}
+
+	if err := ro.configure(cmp.Or(opts.ConfigFile, DefaultConfigFile)); err != nil {
+		return nil, err
+	}
+	if opts.TemplateFile != "" {
+		var err error
+		if ro.mdTemplate, err = loadReportTemplate(opts.TemplateFile); err != nil {
+			return nil, err
+		}
+	}
+	return ro, nil
+}
+
+func resolveDBPath(path string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("error expanding ~ in %s: %w", path, err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+
+	dir := filepath.Dir(path)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("database directory %s does not exist", dir)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("database directory %s is not a directory", dir)
+	}
+
+	return path, nil
+}
+
+func resolveOutputPath(output, defaultName string) (string, error) {
+	if output == "" {
+		return defaultName, nil
+	}
+
+	info, err := os.Stat(output)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	isDir := err == nil && info.IsDir()
+	if err != nil && os.IsPathSeparator(output[len(output)-1]) {
+		if err := os.MkdirAll(output, 0o755); err != nil {
+			return "", fmt.Errorf("error creating output directory %s: %w", output, err)
+		}
+		isDir = true
+	}
+
+	target := output
+	if isDir {
+		target = filepath.Join(output, defaultName)
+	}
+
+	if info, err := os.Stat(target); err == nil && info.IsDir() {
+		return "", fmt.Errorf("output path %s is a directory, expected a file", target)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return "", fmt.Errorf("error creating output directory %s: %w", filepath.Dir(target), err)
+	}
+
+	return target, nil
+}
+
+// ParseFormats returns the report formats of a comma-separated --format
+// list of md, html, json, and csv.
+func ParseFormats(s string) ([]string, error) {
+	var formats []string
+	for _, f := range strings.Split(s, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		switch f {
+		case "md", "html", "json", "csv":
+			formats = append(formats, f)
+		case "":
+		default:
+			return nil, fmt.Errorf("unsupported format %q, expected md, html, json, or csv", f)
+		}
+	}
+	if len(formats) == 0 {
+		return nil, fmt.Errorf("no report format given")
+	}
+	return formats, nil
+}
+
+// ParseDelimiter returns the CSV delimiter for --delimiter: a comma,
+// semicolon, or tab, given as the character or its name. Empty returns 0,
+// which detects the delimiter from the header line.
+func ParseDelimiter(s string) (rune, error) {
+	switch strings.ToLower(s) {
+	case "":
+		return 0, nil
+	case ",", "comma":
+		return ',', nil
+	case ";", "semicolon":
+		return ';', nil
+	case "\t", "tab":
+		return '\t', nil
+	}
+	return 0, fmt.Errorf("unsupported delimiter %q, expected \",\", \";\", or \"tab\"", s)
+}
+
+func withExt(path, ext string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + "." + ext
+}
+
+type runOptions struct {
+	output  string
+	formats []string
+	// workspace, if set, replaces the workspace name from the overview
+	// file, which is also the key that periods are stored and compared by.
+	workspace string
+	latest    bool
+	force     bool
+	noAI      bool
+	// failOnAIError makes a failed AI call fail the report instead of
+	// adding a warning banner to it.
+	failOnAIError bool
+	// quiet suppresses the success and summary lines.
+	quiet bool
+	// ingestOnly stores the CSV files without writing a report.
+	ingestOnly bool
+	// open opens the first written report file.
+	open bool
+	// appendMD writes the Markdown report as a section of the workspace's
+	// master report instead of a file of its own.
+	appendMD bool
+	// manifest writes a manifest next to each report.
+	manifest    bool
+	dumpPrompts io.Writer
+	// cache reuses stored AI responses to identical prompts.
+	cache bool
+	// strict turns warnings about invalid numbers into an error.
+	strict bool
+	stream bool
+	// redactDB stores the posts without their text.
+	redactDB bool
+	// stdin is read for the export named stdinName; nil reads os.Stdin.
+	stdin io.Reader
+	// period, if set, is the name the period is taken from instead of the
+	// overview file's name, for input from stdin.
+	period string
+	// metrics collects the key numbers of each report written.
+	metrics []runMetrics
+	// dbPath and configPath are recorded in the manifest. configPath is
+	// empty if no configuration file was found.
+	dbPath     string
+	configPath string
+	parse      parser.Options
+	report     ReportOptions
+	// reportFlags are the report options as given, before the config
+	// filled in its defaults, for applying a workspace's config.
+	reportFlags ReportOptions
+	// explicitTop keeps report.Top over the config's top setting.
+	explicitTop bool
+	// baseConfig is the configuration file as read, before Validate, and
+	// configDir the directory its prompt templates are relative to.
+	baseConfig *model.Config
+	configDir  string
+	config     *model.Config
+	// generator, if set, replaces the API client of every configuration.
+	generator insights.Generator
+	ai        insights.Generator
+	prompts   *insights.Prompts
+	// mdTemplate replaces the built-in Markdown report template if set.
+	mdTemplate *template.Template
+}
+
+func processWorkspace(ctx context.Context, db *sql.DB, param string, opts *runOptions) error {
+	overviewFile, postsFile, hashtagFile, cleanup, err := findCSVFiles(param, opts.latest)
+	if err != nil {
+		return fmt.Errorf("error finding CSV files: %w", err)
+	}
+	defer cleanup()
+	return processFiles(ctx, db, param, overviewFile, postsFile, hashtagFile, opts)
+}
+
+// processFiles writes the reports of the three CSV files found in param.
+// A file is stdinName if it is read from stdin, and a skipped posts or
+// hashtags file is empty.
+func processFiles(ctx context.Context, db *sql.DB, param, overviewFile, postsFile, hashtagFile string, opts *runOptions) error {
+	slog.Info("found CSV files", "overview", overviewFile, "posts", postsFile, "hashtags", hashtagFile)
+
+	files := namedFiles(overviewFile, postsFile, hashtagFile)
+	if opts.period != "" && !slices.Contains(files, opts.period) {
+		files = append([]string{opts.period}, files...)
+	}
+	if err := checkSamePeriod(files...); err != nil {
+		if !opts.force {
+			return err
+		}
+		slog.Warn("processing CSV files from different periods", "err", err)
+	}
+
+	if opts.ingestOnly {
+		in, err := ingestFiles(ctx, db, overviewFile, postsFile, hashtagFile, opts, true)
+		if err != nil {
+			return err
+		}
+		if !opts.quiet {
+			printStored(cleanWorkspaceName(in.overview.WorkspaceName), extractMonthFromFilename(in.periodName), in.saved)
+		}
+		logWarnings(in.warnings)
+		return nil
+	}
+
+	reportData, in, err := buildReport(ctx, db, overviewFile, postsFile, hashtagFile, opts, true)
+	if err != nil {
+		return err
+	}
+
+	reportFilename, outputs, err := writeReports(reportData, opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.manifest {
+		m, err := newManifest(reportData, param, overviewFile, postsFile, hashtagFile, outputs, opts)
+		if err != nil {
+			return fmt.Errorf("error creating manifest: %w", err)
+		}
+		filename := manifestFilename(reportFilename)
+		if err := writeManifest(m, filename); err != nil {
+			return fmt.Errorf("error writing manifest: %w", err)
+		}
+		if !opts.quiet {
+			fmt.Printf("Manifest written successfully: %s\n", filename)
+		}
+	}
+
+	opts.metrics = append(opts.metrics, newRunMetrics(reportData, reportData.PeriodKey, in.posts, in.hashtags, !opts.noAI))
+
+	if !opts.quiet {
+		printStored(reportData.Workspace, reportData.Month, in.saved)
+		if reportData.HasPrevious {
+			fmt.Printf("Month-over-month changes computed against %s\n", reportData.PreviousPeriod)
+		} else {
+			fmt.Println("Month-over-month changes skipped: no previous period stored")
+		}
+	}
+
+	logWarnings(in.warnings)
+	if opts.open && len(outputs) > 0 {
+		openReport(outputs[0])
+	}
+
+	if reportData.AIWarning != "" {
+		return fmt.Errorf("report for %s, %s written with placeholder text: %w", reportData.Workspace, reportData.Month, ErrAIFailed)
+	}
+
+	return nil
+}
+
+// writeReports writes reportData in each of the formats of opts. It returns
+// the report's filename without the format's extension and the files it
+// wrote.
+func writeReports(reportData *model.ReportData, opts *runOptions) (string, []string, error) {
+	reportFilename, err := resolveOutputPath(opts.output, generateReportFilename(reportData.Workspace, reportData.PeriodKey, opts.formats[0]))
+	if err != nil {
+		return "", nil, fmt.Errorf("error resolving output path: %w", err)
+	}
+
+	var outputs []string
+	for _, f := range opts.formats {
+		if f == "md" && opts.appendMD {
+			filename, err := writeMasterReport(reportData, opts)
+			if err != nil {
+				return "", outputs, fmt.Errorf("error appending report: %w", err)
+			}
+			if !opts.quiet {
+				fmt.Printf("Report section for %s written successfully: %s\n", reportData.Month, filename)
+			}
+			outputs = append(outputs, filename)
+			continue
+		}
+		filename := withExt(reportFilename, f)
+		if err := generateReport(reportData, filename, f, opts.mdTemplate); err != nil {
+			return "", outputs, fmt.Errorf("error generating report: %w", err)
+		}
+		if !opts.quiet {
+			fmt.Printf("Report generated successfully: %s\n", filename)
+		}
+		outputs = append(outputs, filename)
+	}
+	return reportFilename, outputs, nil
+}
+
+// printStored prints what was stored of a period.
+func printStored(workspace, month string, saved store.SaveResult) {
+	fmt.Printf("Stored %s for %s: %d countries, %d posts, %d hashtags\n", month, workspace, saved.Countries, saved.Posts, saved.Hashtags)
+	if saved.Months > 0 {
+		fmt.Printf("Stored the totals of %d earlier months from the same overview export\n", saved.Months)
+	}
+}
+
+// logWarnings logs the parse warnings and the number of skipped rows.
+func logWarnings(warnings []parser.Warning) {
+	for _, w := range warnings {
+		slog.Warn(warningText(w))
+	}
+	if n := parser.SkippedRows(warnings); n > 0 {
+		slog.Warn("skipped CSV rows that could not be read; run with --strict-csv to fail instead", "rows", n)
+	}
+}
+
+// writeMasterReport renders the Markdown report and upserts it into the
+// workspace's master report. It returns the master report's filename.
+func writeMasterReport(data *model.ReportData, opts *runOptions) (string, error) {
+	filename, err := resolveOutputPath(opts.output, masterReportFilename(data.Workspace))
+	if err != nil {
+		return "", err
+	}
+	filename = withExt(filename, "md")
+
+	content, err := renderFormat(data, "md", opts.mdTemplate)
+	if err != nil {
+		return "", err
+	}
+	return filename, appendReport(content, filename, data.Workspace, data.Month)
+}
+
+// buildReport parses the three CSV files and prepares the report data,
+// including the AI texts unless disabled. With save set, the period is
+// stored in the database first. The parsed content is returned as well,
+// with the number of stored rows and the warnings, which list the cells
+// that were read as zero because they do not hold a number, and the rows
+// that were skipped; with opts.strict, invalid numbers fail the report
+// instead.
+func buildReport(ctx context.Context, db *sql.DB, overviewFile, postsFile, hashtagFile string, opts *runOptions, save bool) (*model.ReportData, *ingested, error) {
+	in, err := ingestFiles(ctx, db, overviewFile, postsFile, hashtagFile, opts, save)
+	if err != nil {
+		return nil, in, err
+	}
+	reportData, err := generateReportData(ctx, db, in.overview, in.posts, in.hashtags, in.periodName, opts)
+	if err != nil {
+		return nil, in, err
+	}
+	return reportData, in, nil
+}
+
+// ingested is the content of the CSV files of one period.
+type ingested struct {
+	overview *model.OverviewData
+	posts    []model.PostData
+	hashtags []model.HashtagData
+	// periodName is the name the period is taken from, in the form of
+	// Publer's filenames.
+	periodName string
+	saved      store.SaveResult
+	warnings   []parser.Warning
+}
+
+// ingestFiles parses the three CSV files and, with save set, stores their
+// period in the database. The returned value is never nil, so that the
+// stored rows are known even if saving fails.
+func ingestFiles(ctx context.Context, db *sql.DB, overviewFile, postsFile, hashtagFile string, opts *runOptions, save bool) (*ingested, error) {
+	in := &ingested{}
+	overviewData, warnings, err := opts.readOverview(overviewFile)
+	if err != nil {
+		return in, fmt.Errorf("error reading overview file: %w", err)
+	}
+	if opts.workspace != "" {
+		overviewData.WorkspaceName = opts.workspace
+	}
+	slog.Debug("read overview file", "workspace", overviewData.WorkspaceName, "countries", len(overviewData.TopCountries))
+
+	postsData, postsWarnings, err := opts.readPosts(postsFile)
+	if err != nil {
+		return in, fmt.Errorf("error reading post insights file: %w", err)
+	}
+	slog.Debug("read post insights file", "posts", len(postsData))
+	if n := opts.report.Network; n != "" && postsData != nil && len(filterPostsByNetwork(postsData, n)) == 0 {
+		return in, fmt.Errorf("no %s posts in %s", platformName(n), postsFile)
+	}
+
+	hashtagData, hashtagWarnings, err := opts.readHashtags(hashtagFile)
+	if err != nil {
+		return in, fmt.Errorf("error reading hashtag analysis file: %w", err)
+	}
+	slog.Debug("read hashtag analysis file", "hashtags", len(hashtagData))
+
+	warnings = slices.Concat(warnings, postsWarnings, hashtagWarnings)
+	if invalid := invalidNumbers(warnings); opts.strict && len(invalid) > 0 {
+		texts := make([]string, len(invalid))
+		for i, w := range invalid {
+			texts[i] = warningText(w)
+		}
+		return in, fmt.Errorf("%d invalid numbers in the CSV files (--strict):\n  %s", len(invalid), strings.Join(texts, "\n  "))
+	}
+
+	// A multi-month export reports on its latest month and stores the
+	// others, so that the comparisons find them.
+	periodName := opts.periodSource(overviewFile)
+	if months := overviewData.Months; len(months) > 0 {
+		start, err := time.Parse("2006-01", overviewData.Period)
+		if err != nil {
+			return in, fmt.Errorf("error reading overview file: %w", err)
+		}
+		periodName = monthRange(start)
+		slog.Info("read multi-month overview", "months", len(months), "first", months[0].Period, "last", overviewData.Period)
+	}
+	period, err := extractDateFromFilename(periodName)
+	if err != nil {
+		return in, fmt.Errorf("error extracting period from filename: %w", err)
+	}
+	if overviewData.PeriodStart, overviewData.PeriodEnd, err = dateRangeFromFilename(periodName); err != nil || overviewData.PeriodEnd.Before(overviewData.PeriodStart) {
+		slog.Warn("storing the period as a whole month, because its end date cannot be read", "period", extractPeriodFromFilename(periodName))
+		overviewData.PeriodStart, overviewData.PeriodEnd = time.Time{}, time.Time{}
+	}
+	slog.Info("detected period", "period", period)
+
+	if save {
+		stored := postsData
+		if opts.redactDB && stored != nil {
+			stored = slices.Clone(stored)
+			for i := range stored {
+				stored[i].PostText = "[redacted]"
+			}
+		}
+		if in.saved, err = store.SavePeriod(ctx, db, period, overviewData, stored, hashtagData); err != nil {
+			return in, err
+		}
+		slog.Info("stored period", "workspace", overviewData.WorkspaceName, "period", period, "countries", in.saved.Countries, "accounts", in.saved.Accounts, "posts", in.saved.Posts, "hashtags", in.saved.Hashtags)
+		if in.saved.Posts == 0 && postsFile != "" {
+			slog.Warn("no posts stored for period", "file", postsFile)
+		}
+	}
+
+	in.overview, in.posts, in.hashtags = overviewData, postsData, hashtagData
+	in.periodName = periodName
+	in.warnings = warnings
+	return in, nil
+}
+
+// generateReportData prepares the report data of a period, whether read
+// from the CSV files or from the database, including the AI texts unless
+// disabled.
+func generateReportData(ctx context.Context, db *sql.DB, overviewData *model.OverviewData, postsData []model.PostData, hashtagData []model.HashtagData, periodName string, opts *runOptions) (*model.ReportData, error) {
+	reportData := prepareReportData(ctx, db, overviewData, postsData, hashtagData, periodName, opts.report)
+
+	if opts.dumpPrompts != nil {
+		if err := insights.DumpPrompts(opts.dumpPrompts, reportData, opts.prompts); err != nil {
+			return nil, fmt.Errorf("error dumping prompts: %w", err)
+		}
+	}
+
+	insightsText, nextSteps := aiSkippedText, aiSkippedText
+	var failed []string
+	var err, aiErr error
+	if !opts.noAI {
+		insightsText, err = insights.GenerateInsights(ctx, reportData, opts.ai, opts.prompts)
+		if err != nil {
+			slog.Warn("could not generate insights", "err", err)
+			insightsText = "Insights generation failed. Please check API configuration."
+			failed = append(failed, "insights")
+			aiErr = err
+		}
+
+		nextSteps, err = insights.GenerateNextSteps(ctx, reportData, opts.ai, opts.prompts)
+		if err != nil {
+			slog.Warn("could not generate next steps", "err", err)
+			nextSteps = "Next steps generation failed. Please check API configuration."
+			failed = append(failed, "next steps")
+			aiErr = err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("interrupted: %w", err)
+	}
+
+	if len(failed) > 0 {
+		if opts.failOnAIError {
+			return nil, fmt.Errorf("error generating %s: %w", strings.Join(failed, " and "), aiErr)
+		}
+		reportData.AIWarning = fmt.Sprintf("The AI %s could not be generated, so this report contains placeholder text instead. Check the API configuration and run the report again.", strings.Join(failed, " and "))
+	}
+
+	reportData.Insights = insightsText
+	reportData.NextSteps = nextSteps
+
+	return reportData, nil
+}
+
+// storeOptions returns the database settings of the configuration.
+func (o *runOptions) storeOptions() store.Options {
+	return store.Options{BusyTimeout: o.config.Database.BusyTimeout, JournalMode: o.config.Database.JournalMode}
+}
+
+// dbOptions returns the database settings of the configuration file, which
+// is searched for as in a report run. Empty is the default file; if that
+// does not exist, the defaults apply.
+func dbOptions(configFile string) (store.Options, error) {
+	configFile = cmp.Or(configFile, DefaultConfigFile)
+	path, err := findConfigFile(configFile)
+	if errors.Is(err, errNoConfig) && configFile == DefaultConfigFile {
+		return store.Options{}, nil
+	}
+	if err != nil {
+		return store.Options{}, fmt.Errorf("error loading config: %w", err)
+	}
+	config, err := loadConfig(path, false)
+	if err != nil {
+		return store.Options{}, fmt.Errorf("error loading config: %w", err)
+	}
+	return store.Options{BusyTimeout: config.Database.BusyTimeout, JournalMode: config.Database.JournalMode}, nil
+}
+
+// periodSource returns the name that the period is taken from: the overview
+// file's, unless the run reads from stdin.
+func (o *runOptions) periodSource(overviewFile string) string {
+	return cmp.Or(o.period, overviewFile)
+}
+
+// invalidNumbers returns the warnings about cells that were read as zero.
+func invalidNumbers(warnings []parser.Warning) []parser.Warning {
+	var invalid []parser.Warning
+	for _, w := range warnings {
+		if !w.Skipped {
+			invalid = append(invalid, w)
+		}
+	}
+	return invalid
+}
+
+// warningText describes a parse warning with the file's base name, because
+// files extracted from an archive live in a temporary directory.
+func warningText(w parser.Warning) string {
+	w.File = filepath.Base(w.File)
+	return w.String()
+}
+
+// DefaultDBPath returns the database path to use for the --db value path:
+// path itself, else $PUBLER_DB, else analytics.db, with a leading ~
+// expanded. The directory of the database must exist.
+func DefaultDBPath(path string) (string, error) {
+	if path == "" {
+		path = os.Getenv("PUBLER_DB")
+	}
+	if path == "" {
+		path = "analytics.db"
+	}
+	return resolveDBPath(path)
+}
+
+// configure loads the configuration file and, unless the AI is disabled,
+// the prompt templates. With --no-ai, the API settings are not checked. If
+// no default config file is found, the embedded SampleConfig applies, so
+// that a first run only needs the API key variable to be set.
+func (o *runOptions) configure(configFile string) error {
+	path, err := findConfigFile(configFile)
+	name := path
+	switch {
+	case errors.Is(err, errNoConfig) && configFile == DefaultConfigFile:
+		slog.Debug("using the built-in default config", "reason", err)
+		name = "built-in default config (no " + DefaultConfigFile + " found; --init-config writes one)"
+		o.baseConfig, err = parseConfig([]byte(SampleConfig), name, false)
+	case errors.Is(err, errNoConfig) && !o.noAI:
+		return fmt.Errorf("%w; run with --init-config to write a commented sample %s, or with --no-ai to skip the AI sections", err, DefaultConfigFile)
+	case err != nil:
+		return fmt.Errorf("error loading config: %w", err)
+	default:
+		o.baseConfig, err = loadConfig(path, false)
+		o.configPath = path
+	}
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+	o.configDir = filepath.Dir(path)
+	return o.applyConfig(o.baseConfig, name)
+}
+
+// applyConfig makes a copy of config, read from name, the configuration of
+// o, after validating its API settings unless the AI is off, and derives
+// the parser, report, prompt, and AI settings from it.
+func (o *runOptions) applyConfig(config *model.Config, name string) error {
+	c := *config
+	if !o.noAI {
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("error loading config: %s: %w", name, err)
+		}
+	}
+	o.config = &c
+	o.ai = o.generator
+
+	var err error
+	if o.config.Top != nil && !o.explicitTop {
+		o.report.Top = *o.config.Top
+	}
+	o.parse.CountryAliases = o.config.Countries
+	if o.report.Language == "" {
+		if o.report.Language, err = ParseLanguage(o.config.Language); err != nil {
+			return fmt.Errorf("error loading config: %w", err)
+		}
+	}
+	if o.report.RateBasis == "" {
+		if o.report.RateBasis, err = ParseRateBasis(o.config.EngagementRateBasis); err != nil {
+			return fmt.Errorf("error loading config: %w", err)
+		}
+	}
+	for _, c := range []struct {
+		name    string
+		columns model.ColumnMap
+		dst     *map[string]string
+	}{
+		{"overview", o.config.Overview, &o.parse.OverviewColumns},
+		{"posts", o.config.Posts, &o.parse.PostColumns},
+		{"hashtags", o.config.Hashtags, &o.parse.HashtagColumns},
+	} {
+		if *c.dst, err = c.columns.Fields(); err != nil {
+			return fmt.Errorf("error loading config: %s: %w", c.name, err)
+		}
+	}
+
+	if o.noAI && o.dumpPrompts == nil {
+		return nil
+	}
+	o.prompts, err = insights.LoadPrompts(o.config, o.configDir)
+	if err != nil {
+		return fmt.Errorf("error loading prompt templates: %w", err)
+	}
+	if !o.noAI && o.generator == nil {
+		client := insights.NewClient(o.config)
+		client.Stream = o.stream
+		if o.stream && !o.quiet {
+			client.Progress = os.Stderr
+		}
+		o.ai = client
+	}
+	return nil
+}
+
+// forWorkspace returns the options for the workspace in dir. If dir holds
+// a config file of its own, they are a copy of o whose configuration is
+// that file merged on top of o's; otherwise they are o. Prompt templates
+// named by the workspace's file are relative to dir.
+func (o *runOptions) forWorkspace(db *sql.DB, dir string) (*runOptions, error) {
+	path := filepath.Join(dir, DefaultConfigFile)
+	if _, err := os.Stat(path); err != nil {
+		return o, nil
+	}
+	override, err := loadConfig(path, false)
+	if err != nil {
+		return nil, fmt.Errorf("error loading config: %s: %w", path, err)
+	}
+	for _, p := range []*string{&override.Prompts.Insights, &override.Prompts.NextSteps} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+
+	config := *o.baseConfig
+	config.Merge(override)
+
+	w := *o
+	w.report = o.reportFlags
+	w.configPath = path
+	if err := w.applyConfig(&config, path); err != nil {
+		return nil, err
+	}
+	w.useCache(db)
+	slog.Debug("using workspace config", "file", path)
+	return &w, nil
+}
+
+const DefaultConfigFile = "config.yaml"
+
+// errNoConfig marks a configuration file that does not exist.
+var errNoConfig = errors.New("config file not found")
+
+// findConfigFile returns path unchanged unless it is the default, in which
+// case it falls back to the user config directories if ./config.yaml does
+// not exist.
+func findConfigFile(path string) (string, error) {
+	if path != DefaultConfigFile {
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("%w: %s", errNoConfig, path)
+		}
+		return path, nil
+	}
+
+	candidates := []string{path}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		candidates = append(candidates, filepath.Join(xdg, "publer-report", DefaultConfigFile))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(home, ".config", "publer-report", DefaultConfigFile))
+	}
+
+	for _, c := range candidates {
+		if _, err := os.Stat(c); err == nil {
+			return c, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w, tried: %s", errNoConfig, strings.Join(slices.Compact(candidates), ", "))
+}
+
+// loadConfig reads a config file. With validateAPI set, it also checks the
+// API settings and fills in their defaults.
+func loadConfig(filename string, validateAPI bool) (*model.Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(data, filename, validateAPI)
+}
+
+// parseConfig parses the configuration in data. The name identifies it in
+// errors.
+func parseConfig(data []byte, name string, validateAPI bool) (*model.Config, error) {
+	var config model.Config
+	err := yaml.Unmarshal(data, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	if !validateAPI {
+		return &config, nil
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return &config, nil
+}
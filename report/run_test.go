@@ -0,0 +1,214 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/christophberger/publer-analytics-report/store"
+)
+
+func TestMain(m *testing.M) {
+	// The pipeline logs each step; keep the test output to the failures.
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// fakeGenerator stands in for the AI API. It records the prompts it
+// receives and answers each with its response, or fails with its err.
+type fakeGenerator struct {
+	response string
+	err      error
+
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (g *fakeGenerator) Generate(ctx context.Context, prompt string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.prompts = append(g.prompts, prompt)
+	if g.err != nil {
+		return "", g.err
+	}
+	return g.response, nil
+}
+
+// testOptions returns options for a quiet run on input with the report
+// settings of the command line that keep the database, the configuration,
+// and the reports in a temporary directory, so that no user configuration
+// or API key is involved.
+func testOptions(t *testing.T, input string, gen *fakeGenerator) Options {
+	t.Helper()
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(config, []byte("api:\n  auth_required: false\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{
+		Input:      input,
+		Output:     filepath.Join(dir, "reports") + string(filepath.Separator),
+		ConfigFile: config,
+		DBPath:     filepath.Join(dir, "analytics.db"),
+		Quiet:      true,
+		NoCache:    true,
+		// The defaults of the command-line flags.
+		Report: ReportOptions{
+			PostTypes:     ParsePostTypes(DefaultPostTypes),
+			Top:           5,
+			History:       6,
+			RankBy:        DefaultRankBy,
+			HashtagRankBy: DefaultHashtagRankBy,
+			Decimals:      DefaultDecimals,
+		},
+	}
+	if gen != nil {
+		opts.Generator = gen
+	} else {
+		opts.NoAI = true
+	}
+	return opts
+}
+
+// readReport returns the content of the only report in the output
+// directory of opts.
+func readReport(t *testing.T, opts Options) string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(opts.Output, "*.md"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("reports in %s: %v, %v, want one", opts.Output, files, err)
+	}
+	b, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRunPrompts(t *testing.T) {
+	gen := &fakeGenerator{response: "Canned AI text."}
+	opts := testOptions(t, filepath.Join("..", "testdata", "2025-07"), gen)
+	if err := RunContext(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(gen.prompts) != 2 {
+		t.Fatalf("%d prompts sent, want the insights and the next steps", len(gen.prompts))
+	}
+	tests := []struct {
+		name   string
+		prompt string
+		want   []string
+	}{
+		{"insights", gen.prompts[0], []string{"July 2025 (1 Jul 2025 - 31 Jul 2025)", "- Followers: 4750\n", "- Reach: 507\n", "Top performing posts by", "Top hashtags by score:", "Please provide insights"}},
+		{"next steps", gen.prompts[1], []string{"July 2025 (1 Jul 2025 - 31 Jul 2025)", "- Engagements: 105", "Please suggest specific next steps"}},
+	}
+	for _, tt := range tests {
+		for _, want := range tt.want {
+			if !strings.Contains(tt.prompt, want) {
+				t.Errorf("%s prompt lacks %q:\n%s", tt.name, want, tt.prompt)
+			}
+		}
+	}
+	if strings.Contains(gen.prompts[0], "vs. previous month") {
+		t.Error("insights prompt compares with a previous month that is not stored")
+	}
+
+	report := readReport(t, opts)
+	if n := strings.Count(report, "Canned AI text."); n != 2 {
+		t.Errorf("report holds the AI text %d times, want 2", n)
+	}
+}
+
+func TestRunPromptsWithPrevious(t *testing.T) {
+	gen := &fakeGenerator{response: "Canned AI text."}
+	opts := testOptions(t, filepath.Join("..", "testdata", "2025-06"), nil)
+	if err := RunContext(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	opts.Input = filepath.Join("..", "testdata", "2025-07")
+	opts.NoAI, opts.Generator = false, gen
+	if err := RunContext(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(gen.prompts) != 2 {
+		t.Fatalf("%d prompts sent, want 2", len(gen.prompts))
+	}
+	if want := "- Followers: 4750 (+43 vs. previous month)"; !strings.Contains(gen.prompts[0], want) {
+		t.Errorf("insights prompt lacks %q:\n%s", want, gen.prompts[0])
+	}
+}
+
+func TestRunAIFailure(t *testing.T) {
+	apiErr := errors.New("API request failed with status: 503")
+	tests := []struct {
+		name        string
+		failOnError bool
+		report      bool
+	}{
+		{"placeholder", false, true},
+		{"fail on AI error", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := &fakeGenerator{err: apiErr}
+			opts := testOptions(t, filepath.Join("..", "testdata", "2025-07"), gen)
+			opts.FailOnAIError = tt.failOnError
+
+			err := RunContext(context.Background(), opts)
+			if err == nil {
+				t.Fatal("run succeeded despite the AI failure")
+			}
+			if got := errors.Is(err, ErrAIFailed); got != tt.report {
+				t.Errorf("errors.Is(%v, ErrAIFailed) = %v, want %v", err, got, tt.report)
+			}
+			if !tt.report {
+				if !errors.Is(err, apiErr) {
+					t.Errorf("error %v does not wrap the API error", err)
+				}
+				if files, _ := filepath.Glob(filepath.Join(opts.Output, "*.md")); len(files) > 0 {
+					t.Errorf("report written despite FailOnAIError: %v", files)
+				}
+				return
+			}
+
+			report := readReport(t, opts)
+			for _, want := range []string{"could not be generated", "Insights generation failed.", "Next steps generation failed."} {
+				if !strings.Contains(report, want) {
+					t.Errorf("report lacks %q", want)
+				}
+			}
+		})
+	}
+}
+
+func TestDBOptions(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(config, []byte("database:\n  busy_timeout: 2s\n  journal_mode: delete\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts, err := dbOptions(config)
+	if err != nil || opts.BusyTimeout != 2*time.Second || opts.JournalMode != "delete" {
+		t.Errorf("dbOptions(%s) = %+v, %v, want the configured settings", config, opts, err)
+	}
+
+	// A missing default config keeps the defaults; a missing named one is
+	// an error.
+	t.Chdir(t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	if opts, err := dbOptions(""); err != nil || opts != (store.Options{}) {
+		t.Errorf("dbOptions without a config = %+v, %v, want the defaults", opts, err)
+	}
+	if _, err := dbOptions("missing.yaml"); err == nil {
+		t.Error("dbOptions of a missing config file succeeded")
+	}
+}
@@ -0,0 +1,195 @@
+package report
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/christophberger/publer-analytics-report/store"
+)
+
+const maxUploadSize = 32 << 20
+
+var contentTypes = map[string]string{
+	"md":   "text/markdown; charset=utf-8",
+	"html": "text/html; charset=utf-8",
+	"json": "application/json",
+	"csv":  "text/csv; charset=utf-8",
+}
+
+// Serve answers report requests on addr until ctx is canceled. Of opts,
+// the settings for the configuration, the database, parsing, and the report
+// layout apply.
+func Serve(ctx context.Context, addr string, opts Options) error {
+	ro, err := newRunOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	path, err := DefaultDBPath(opts.DBPath)
+	if err != nil {
+		return fmt.Errorf("error resolving database path: %w", err)
+	}
+
+	db, err := store.Open(path, ro.storeOptions())
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer db.Close()
+
+	if err := store.InitSchema(ctx, db); err != nil {
+		return fmt.Errorf("error initializing database: %w", err)
+	}
+	ro.useCache(db)
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /report", &reportHandler{db: db, opts: ro})
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	fmt.Printf("Listening on %s\n", addr)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// reportHandler renders a report from a multipart upload of the three CSV
+// exports. The files keep their Publer names, which carry the period.
+//
+// Query parameters:
+//   - format: md (default), html, json, or csv
+//   - save: store the period in the database, like the CLI does
+type reportHandler struct {
+	db   *sql.DB
+	opts *runOptions
+}
+
+func (h *reportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "md"
+	}
+	contentType, ok := contentTypes[format]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unsupported format %q, expected md, html, json, or csv", format), http.StatusBadRequest)
+		return
+	}
+
+	save := false
+	if v := r.URL.Query().Get("save"); v != "" {
+		var err error
+		if save, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, fmt.Sprintf("invalid save parameter %q", v), http.StatusBadRequest)
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		http.Error(w, fmt.Sprintf("error reading upload: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	dir, err := os.MkdirTemp("", "publer-report-")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	if err := saveUploads(r, dir); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	overviewFile, postsFile, hashtagFile, err := findCSVFilesInDir(dir, false)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error finding CSV files: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := checkSamePeriod(overviewFile, postsFile, hashtagFile); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, in, err := buildReport(r.Context(), h.db, overviewFile, postsFile, hashtagFile, h.opts, save)
+	if err != nil {
+		slog.Error("report failed", "err", err)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	for _, pw := range in.warnings {
+		slog.Warn(warningText(pw))
+	}
+
+	content, err := renderFormat(data, format, h.opts.mdTemplate)
+	if err != nil {
+		slog.Error("rendering failed", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	io.WriteString(w, content)
+}
+
+func saveUploads(r *http.Request, dir string) error {
+	n := 0
+	for _, files := range r.MultipartForm.File {
+		for _, fh := range files {
+			name := filepath.Base(fh.Filename)
+			if !isCSVFile(name) {
+				continue
+			}
+			if err := saveUpload(fh, filepath.Join(dir, name)); err != nil {
+				return fmt.Errorf("error saving %s: %w", name, err)
+			}
+			n++
+		}
+	}
+	if n == 0 {
+		return errors.New("no CSV files in upload")
+	}
+	return nil
+}
+
+func saveUpload(fh *multipart.FileHeader, path string) error {
+	src, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
@@ -0,0 +1,146 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/christophberger/publer-analytics-report/model"
+	"github.com/christophberger/publer-analytics-report/parser"
+)
+
+// stdinName stands for the standard input in place of a CSV filename.
+const stdinName = "-"
+
+// ParseStdinType returns the export type for --stdin: overview, posts, or
+// hashtags.
+func ParseStdinType(s string) (string, error) {
+	switch t := strings.ToLower(strings.TrimSpace(s)); t {
+	case "overview", "posts", "hashtags":
+		return t, nil
+	}
+	return "", fmt.Errorf("unsupported export type %q, expected overview, posts, or hashtags", s)
+}
+
+// stdinFiles returns the overview, posts, and hashtags files of a run that
+// reads the export of opts.StdinType from stdin: stdinName for that one,
+// and the files from opts for the others. A posts or hashtags file that is
+// not given is empty, which skips it. The period is the date range of the
+// first named file, or of opts.Period if set.
+func stdinFiles(opts Options) (overview, posts, hashtags, period string, err error) {
+	files := map[string]*string{"overview": &overview, "posts": &posts, "hashtags": &hashtags}
+	overview, posts, hashtags = opts.OverviewFile, opts.PostsFile, opts.HashtagsFile
+	dst, ok := files[opts.StdinType]
+	if !ok {
+		return "", "", "", "", fmt.Errorf("unsupported export type %q, expected overview, posts, or hashtags", opts.StdinType)
+	}
+	if *dst != "" {
+		return "", "", "", "", fmt.Errorf("the %s export cannot be read from both stdin and %s", opts.StdinType, *dst)
+	}
+	*dst = stdinName
+	if overview == "" {
+		return "", "", "", "", fmt.Errorf("an overview export is required, either on stdin or as a file")
+	}
+
+	if opts.Period != "" {
+		start, err := time.Parse("2006-01", opts.Period)
+		if err != nil {
+			return "", "", "", "", fmt.Errorf("invalid period %q, expected YYYY-MM", opts.Period)
+		}
+		return overview, posts, hashtags, monthRange(start), nil
+	}
+	for _, f := range []string{overview, posts, hashtags} {
+		if f != "" && f != stdinName {
+			return overview, posts, hashtags, f, nil
+		}
+	}
+	return "", "", "", "", fmt.Errorf("the period of the %s export on stdin is unknown; name another export file or give the period as YYYY-MM", opts.StdinType)
+}
+
+// monthRange returns the date range of the month starting at start, in the
+// form of Publer's filenames, such as "1 Jul 2025 - 31 Jul 2025".
+func monthRange(start time.Time) string {
+	return dateRange(start, start.AddDate(0, 1, -1))
+}
+
+// dateRange returns the period from start to end in the form of Publer's
+// filenames.
+func dateRange(start, end time.Time) string {
+	return start.Format("2 Jan 2006") + " - " + end.Format("2 Jan 2006")
+}
+
+// namedFiles returns the files that are neither read from stdin nor
+// skipped.
+func namedFiles(files ...string) []string {
+	var named []string
+	for _, f := range files {
+		if f != "" && f != stdinName {
+			named = append(named, f)
+		}
+	}
+	return named
+}
+
+func (o *runOptions) stdinReader() io.Reader {
+	if o.stdin == nil {
+		return os.Stdin
+	}
+	return o.stdin
+}
+
+// readOverview reads the overview export in filename, or from stdin.
+func (o *runOptions) readOverview(filename string) (*model.OverviewData, []parser.Warning, error) {
+	if filename == stdinName {
+		return parser.ReadOverview(o.stdinReader(), "stdin", o.parse)
+	}
+	return parser.ReadOverviewFile(filename, o.parse)
+}
+
+// readPosts reads the post insights export in filename, or from stdin. An
+// empty filename skips the export and returns nil, which keeps the stored
+// posts of the period; an empty export returns an empty slice instead.
+func (o *runOptions) readPosts(filename string) ([]model.PostData, []parser.Warning, error) {
+	var posts []model.PostData
+	var warnings []parser.Warning
+	var err error
+	switch filename {
+	case "":
+		return nil, nil, nil
+	case stdinName:
+		posts, warnings, err = parser.ReadPostInsights(o.stdinReader(), "stdin", o.parse)
+	default:
+		posts, warnings, err = parser.ReadPostInsightsFile(filename, o.parse)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if posts == nil {
+		posts = []model.PostData{}
+	}
+	return posts, warnings, nil
+}
+
+// readHashtags reads the hashtag analysis export in filename, or from
+// stdin. Like readPosts, it returns nil only for a skipped export.
+func (o *runOptions) readHashtags(filename string) ([]model.HashtagData, []parser.Warning, error) {
+	var hashtags []model.HashtagData
+	var warnings []parser.Warning
+	var err error
+	switch filename {
+	case "":
+		return nil, nil, nil
+	case stdinName:
+		hashtags, warnings, err = parser.ReadHashtagAnalysis(o.stdinReader(), "stdin", o.parse)
+	default:
+		hashtags, warnings, err = parser.ReadHashtagAnalysisFile(filename, o.parse)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if hashtags == nil {
+		hashtags = []model.HashtagData{}
+	}
+	return hashtags, warnings, nil
+}
@@ -0,0 +1,103 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/christophberger/publer-analytics-report/parser"
+	"github.com/christophberger/publer-analytics-report/store"
+)
+
+// RunStored writes the report of a period stored by an earlier run, as the
+// report subcommand does, without reading CSV files. opts.Period selects
+// the period as YYYY-MM, or the latest stored one if empty, and
+// opts.Workspace the workspace, or the only one in the database if empty.
+// The comparisons and AI texts are the same as for a report from the CSV
+// files, but posts stored with RedactDB keep their redacted text.
+func RunStored(ctx context.Context, opts Options) error {
+	if opts.Input != "" || opts.StdinType != "" || opts.Recursive {
+		return errors.New("a report from the database reads no CSV files")
+	}
+	if opts.Period != "" {
+		if _, err := time.Parse("2006-01", opts.Period); err != nil {
+			return fmt.Errorf("invalid period %q, expected YYYY-MM", opts.Period)
+		}
+	}
+
+	ro, err := newRunOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	dbPath, err := DefaultDBPath(opts.DBPath)
+	if err != nil {
+		return fmt.Errorf("error resolving database path: %w", err)
+	}
+	ro.dbPath = dbPath
+
+	db, err := store.Open(dbPath, ro.storeOptions())
+	if err != nil {
+		return fmt.Errorf("error opening database: %w", err)
+	}
+	defer db.Close()
+
+	if err := store.InitSchema(ctx, db); err != nil {
+		return fmt.Errorf("error initializing database: %w", err)
+	}
+	ro.useCache(db)
+
+	ws, err := pickWorkspace(ctx, db, opts.Workspace)
+	if err != nil {
+		return err
+	}
+	period := opts.Period
+	if period == "" {
+		periods, err := store.ListPeriods(ctx, db, ws)
+		if err != nil {
+			return fmt.Errorf("error listing periods: %w", err)
+		}
+		if len(periods) == 0 {
+			return fmt.Errorf("no stored periods for %s", ws)
+		}
+		period = periods[len(periods)-1].Period
+	}
+
+	overview, posts, hashtags, err := store.GetPeriod(ctx, db, ws, period)
+	if err != nil {
+		return err
+	}
+	if overview == nil {
+		return fmt.Errorf("no stored period %s for %s; run periods to list them", period, ws)
+	}
+	for i := range posts {
+		posts[i].PostedAt = parser.ParsePostDate(posts[i].Date)
+	}
+
+	start, err := time.Parse("2006-01", period)
+	if err != nil {
+		return fmt.Errorf("invalid stored period %q: %w", period, err)
+	}
+	periodName := monthRange(start)
+	if !overview.PeriodStart.IsZero() && !overview.PeriodEnd.IsZero() {
+		periodName = dateRange(overview.PeriodStart, overview.PeriodEnd)
+	}
+
+	reportData, err := generateReportData(ctx, db, overview, posts, hashtags, periodName, ro)
+	if err != nil {
+		return err
+	}
+	_, outputs, err := writeReports(reportData, ro)
+	if err != nil {
+		return err
+	}
+	if ro.open && len(outputs) > 0 {
+		openReport(outputs[0])
+	}
+
+	if reportData.AIWarning != "" {
+		return fmt.Errorf("report for %s, %s written with placeholder text: %w", reportData.Workspace, reportData.Month, ErrAIFailed)
+	}
+	return nil
+}
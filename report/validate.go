@@ -0,0 +1,72 @@
+package report
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/christophberger/publer-analytics-report/parser"
+)
+
+// Validate parses the CSV files of opts.Input and prints what they contain,
+// including the cells that do not hold a number, without storing anything.
+// The column mappings and country aliases of the configuration apply as in
+// a report run. Of opts, Input, ConfigFile, Latest, Force, Delimiter, Strict,
+// and StrictCSV apply.
+func Validate(opts Options) error {
+	ro := &runOptions{noAI: true, parse: parser.Options{Delimiter: opts.Delimiter, StrictRows: opts.StrictCSV}}
+	if err := ro.configure(cmp.Or(opts.ConfigFile, DefaultConfigFile)); err != nil {
+		return err
+	}
+	popts := ro.parse
+
+	overviewFile, postsFile, hashtagFile, cleanup, err := findCSVFiles(opts.Input, opts.Latest)
+	if err != nil {
+		return fmt.Errorf("error finding CSV files: %w", err)
+	}
+	defer cleanup()
+
+	if err := checkSamePeriod(overviewFile, postsFile, hashtagFile); err != nil && !opts.Force {
+		return err
+	}
+
+	period, err := extractDateFromFilename(overviewFile)
+	if err != nil {
+		return fmt.Errorf("error extracting period from filename: %w", err)
+	}
+
+	overviewData, overviewWarnings, err := parser.ReadOverviewFile(overviewFile, popts)
+	if err != nil {
+		return fmt.Errorf("error reading overview file: %w", err)
+	}
+
+	postsData, postsWarnings, err := parser.ReadPostInsightsFile(postsFile, popts)
+	if err != nil {
+		return fmt.Errorf("error reading post insights file %s: %w", postsFile, err)
+	}
+
+	hashtagData, hashtagWarnings, err := parser.ReadHashtagAnalysisFile(hashtagFile, popts)
+	if err != nil {
+		return fmt.Errorf("error reading hashtag analysis file %s: %w", hashtagFile, err)
+	}
+	warnings := slices.Concat(overviewWarnings, postsWarnings, hashtagWarnings)
+
+	fmt.Printf("Workspace: %s\n", overviewData.WorkspaceName)
+	fmt.Printf("Period:    %s (%s)\n", period, extractPeriodFromFilename(overviewFile))
+	if months := overviewData.Months; len(months) > 0 {
+		fmt.Printf("Months:    %d, %s to %s; the report covers %s\n", len(months), months[0].Period, overviewData.Period, overviewData.Period)
+	}
+	fmt.Printf("Countries: %d\n", len(overviewData.TopCountries))
+	fmt.Printf("Posts:     %d\n", len(postsData))
+	fmt.Printf("Hashtags:  %d\n", len(hashtagData))
+	fmt.Printf("Skipped:   %d rows\n", parser.SkippedRows(warnings))
+	fmt.Printf("Warnings:  %d\n", len(warnings))
+	for _, w := range warnings {
+		fmt.Printf("  %s\n", warningText(w))
+	}
+
+	if invalid := invalidNumbers(warnings); opts.Strict && len(invalid) > 0 {
+		return fmt.Errorf("%d invalid numbers in the CSV files", len(invalid))
+	}
+	return nil
+}
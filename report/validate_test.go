@@ -0,0 +1,44 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestValidateColumnMappings checks that validate reads the CSV files with
+// the column mappings of the configuration, as a report run does.
+func TestValidateColumnMappings(t *testing.T) {
+	input := t.TempDir()
+	files, err := filepath.Glob(filepath.Join("..", "testdata", "2025-07", "*.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(f, "Post Insights") {
+			data = []byte(strings.Replace(string(data), ",Reactions,", ",Hearts,", 1))
+		}
+		if err := os.WriteFile(filepath.Join(input, filepath.Base(f)), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := testOptions(t, input, nil)
+	if err := os.WriteFile(opts.ConfigFile, []byte("posts:\n  reactions_column: Hearts\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(opts); err != nil {
+		t.Errorf("Validate with the renamed column: %v", err)
+	}
+
+	// A configured label that is missing from the export is an error.
+	opts.Input = filepath.Join("..", "testdata", "2025-07")
+	if err := Validate(opts); err == nil || !strings.Contains(err.Error(), "Hearts") {
+		t.Errorf("Validate without the renamed column: error %v, want one naming the label", err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/christophberger/publer-analytics-report/report"
+)
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	dbPath := fs.String("db", "", "path to the SQLite database (default $PUBLER_DB or analytics.db)")
+	configFile := fs.String("config", report.DefaultConfigFile, "path to the configuration file (default searches ./config.yaml, then the user config directory)")
+	postTypes := fs.String("post-types", report.DefaultPostTypes, `comma-separated post types to rank as top posts, or "all"`)
+	hashtagRankBy := fs.String("hashtag-rank-by", report.DefaultHashtagRankBy, "metric to rank the top hashtags by: score, reach, or engagement")
+	rankBy := fs.String("rank-by", report.DefaultRankBy, "metric to rank the top posts by: reactions, engagements, reach, or clicks")
+	top := fs.Int("top", 5, "number of top posts, hashtags, and countries to show (0 or less shows all)")
+	decimals := fs.Int("decimals", report.DefaultDecimals, "number of decimal places of percentages and averages in the report")
+	history := fs.Int("history", 6, "number of stored months to show in the trend table (0 hides it)")
+	templateFile := fs.String("template", "", "custom text/template file for the Markdown report")
+	delimiter := fs.String("delimiter", "", `CSV field separator: ",", ";", or "tab" (default detects it per file)`)
+	noAI := fs.Bool("no-ai", false, "skip the AI insights and next steps")
+	verbose := fs.Bool("verbose", false, "log discovered files, row counts, periods, and API timing to stderr")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s serve [flags]\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	setupLogging(*verbose, false)
+
+	delim, err := report.ParseDelimiter(*delimiter)
+	if err != nil {
+		return usageError{fmt.Errorf("error parsing delimiter: %w", err)}
+	}
+
+	by, err := report.ParseRankBy(*rankBy)
+	if err != nil {
+		return usageError{fmt.Errorf("error parsing ranking: %w", err)}
+	}
+	hashtagBy, err := report.ParseHashtagRankBy(*hashtagRankBy)
+	if err != nil {
+		return usageError{fmt.Errorf("error parsing hashtag ranking: %w", err)}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return report.Serve(ctx, *addr, report.Options{
+		DBPath:       *dbPath,
+		ConfigFile:   *configFile,
+		TemplateFile: *templateFile,
+		Delimiter:    delim,
+		NoAI:         *noAI,
+		ExplicitTop:  isFlagSet(fs, "top"),
+		Report: report.ReportOptions{
+			PostTypes:     report.ParsePostTypes(*postTypes),
+			Top:           *top,
+			History:       *history,
+			RankBy:        by,
+			HashtagRankBy: hashtagBy,
+			Decimals:      *decimals,
+		},
+	})
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+func createAICacheTable(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS ai_cache (key TEXT PRIMARY KEY, model TEXT, response TEXT NOT NULL, created_at TEXT NOT NULL);")
+	return err
+}
+
+// CachedResponse returns the AI response stored under key, or false if
+// there is none.
+func CachedResponse(ctx context.Context, db *sql.DB, key string) (string, bool, error) {
+	var response string
+	err := db.QueryRowContext(ctx, "SELECT response FROM ai_cache WHERE key = ?", key).Scan(&response)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return response, true, nil
+}
+
+// SaveCachedResponse stores an AI response under key, replacing an older
+// one, with the current time as its creation time.
+func SaveCachedResponse(ctx context.Context, db *sql.DB, key, model, response string) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO ai_cache(key, model, response, created_at) VALUES(?, ?, ?, datetime('now'))
+		ON CONFLICT(key) DO UPDATE SET model = excluded.model, response = excluded.response, created_at = excluded.created_at`, key, model, response)
+	return err
+}
+
+// ClearCache deletes all cached AI responses and returns how many there were.
+func ClearCache(ctx context.Context, db *sql.DB) (int64, error) {
+	res, err := db.ExecContext(ctx, "DELETE FROM ai_cache")
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Dump is the content of a database: the schema version it was written
+// with and the rows of each table, keyed by column name, so that a dump
+// can be loaded into a later schema with more columns.
+type Dump struct {
+	SchemaVersion int         `json:"schema_version"`
+	ExportedAt    time.Time   `json:"exported_at,omitzero"`
+	Tables        []DumpTable `json:"tables"`
+}
+
+type DumpTable struct {
+	Name string           `json:"name"`
+	Rows []map[string]any `json:"rows"`
+}
+
+// dumpTables are the tables a dump holds, in the order they are written.
+// schema_version is not among them, as Import recreates it.
+var dumpTables = []string{"overview", "countries", "accounts", "posts", "hashtags", "ai_cache"}
+
+// now returns the current time. Tests replace it to freeze the clock.
+var now = time.Now
+
+// Export reads every table of db that exists at its schema version. It
+// does not modify the database, so it also works on one that still needs
+// migrations.
+func Export(ctx context.Context, db *sql.DB) (*Dump, error) {
+	dump := &Dump{ExportedAt: now().UTC().Truncate(time.Second)}
+
+	cols, err := tableColumns(ctx, db, "schema_version")
+	if err != nil {
+		return nil, err
+	}
+	if len(cols) > 0 {
+		if err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&dump.SchemaVersion); err != nil {
+			return nil, fmt.Errorf("error reading schema version: %w", err)
+		}
+	}
+
+	for _, table := range dumpTables {
+		cols, err := tableColumns(ctx, db, table)
+		if err != nil {
+			return nil, err
+		}
+		if len(cols) == 0 {
+			continue
+		}
+		rows, err := exportTable(ctx, db, table)
+		if err != nil {
+			return nil, fmt.Errorf("error reading table %s: %w", table, err)
+		}
+		dump.Tables = append(dump.Tables, DumpTable{Name: table, Rows: rows})
+	}
+	return dump, nil
+}
+
+func exportTable(ctx context.Context, db *sql.DB, table string) ([]map[string]any, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s ORDER BY rowid", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := []map[string]any{}
+	for rows.Next() {
+		values := make([]any, len(names))
+		ptrs := make([]any, len(names))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]any, len(names))
+		for i, name := range names {
+			row[name] = values[i]
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// Import loads dump into db, which must be a new, empty database, and
+// returns the number of rows loaded. The schema is created at the dump's
+// version before the rows are inserted and migrated to the latest version
+// afterwards, so that the migrations fill in what the older schema lacked.
+func Import(ctx context.Context, db *sql.DB, dump *Dump) (int, error) {
+	if dump.SchemaVersion < 1 {
+		return 0, errors.New("the dump has no schema version; run migrate on the exported database and export it again")
+	}
+	if current, err := SchemaVersion(ctx, db); err != nil {
+		return 0, fmt.Errorf("error reading schema version: %w", err)
+	} else if current > 0 {
+		return 0, fmt.Errorf("the database is not empty (schema version %d)", current)
+	}
+	if _, err := MigrateTo(ctx, db, dump.SchemaVersion); err != nil {
+		return 0, err
+	}
+
+	columns := make([]map[string]bool, len(dump.Tables))
+	for i, t := range dump.Tables {
+		if !slices.Contains(dumpTables, t.Name) {
+			return 0, fmt.Errorf("unknown table %q", t.Name)
+		}
+		cols, err := tableColumns(ctx, db, t.Name)
+		if err != nil {
+			return 0, err
+		}
+		if len(cols) == 0 {
+			return 0, fmt.Errorf("table %s does not exist at schema version %d", t.Name, dump.SchemaVersion)
+		}
+		columns[i] = cols
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	n := 0
+	for ti, t := range dump.Tables {
+		for i, row := range t.Rows {
+			if err := importRow(ctx, tx, t.Name, columns[ti], row); err != nil {
+				return 0, fmt.Errorf("table %s, row %d: %w", t.Name, i+1, err)
+			}
+			n++
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	if _, err := Migrate(ctx, db); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+func importRow(ctx context.Context, tx *sql.Tx, table string, cols map[string]bool, row map[string]any) error {
+	names := slices.Sorted(maps.Keys(row))
+	args := make([]any, len(names))
+	for i, name := range names {
+		if !cols[name] {
+			return fmt.Errorf("unknown column %q", name)
+		}
+		v, err := importValue(row[name])
+		if err != nil {
+			return fmt.Errorf("column %s: %w", name, err)
+		}
+		args[i] = v
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s(%s) VALUES(%s)", table, strings.Join(names, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", "))
+	_, err := tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+// importValue turns a JSON value into a column value. Numbers decoded
+// with json.Decoder.UseNumber stay integers if they have no fraction.
+func importValue(v any) (any, error) {
+	switch v := v.(type) {
+	case nil, string, bool, int64, float64:
+		return v, nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	}
+	return nil, fmt.Errorf("unsupported value %v of type %T", v, v)
+}
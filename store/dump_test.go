@@ -0,0 +1,156 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// roundTrip encodes dump as JSON and decodes it again the way ImportDB
+// does.
+func roundTrip(t *testing.T, dump *Dump) *Dump {
+	t.Helper()
+	b, err := json.Marshal(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Dump
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	return &decoded
+}
+
+func TestDumpRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := openFixture(t, "v1.sql")
+	if _, err := Migrate(ctx, src); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.Exec("INSERT INTO accounts(workspace, period, account, network, followers, reach, engagements, engagement_rate) VALUES('Acme', '2025-07', 'acme', 'Instagram', 800, 4000, 300, 7.5)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveCachedResponse(ctx, src, "key", "model", "insights\nwith \"quotes\""); err != nil {
+		t.Fatal(err)
+	}
+
+	dump, err := Export(ctx, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dump.SchemaVersion != LatestSchemaVersion() {
+		t.Errorf("exported schema version %d, want %d", dump.SchemaVersion, LatestSchemaVersion())
+	}
+
+	dst := openTestDB(t)
+	n, err := Import(ctx, dst, roundTrip(t, dump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := 0
+	for _, table := range dump.Tables {
+		rows += len(table.Rows)
+	}
+	if n != rows {
+		t.Errorf("imported %d rows, want %d", n, rows)
+	}
+
+	got, err := Export(ctx, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SchemaVersion != dump.SchemaVersion {
+		t.Errorf("schema version after import = %d, want %d", got.SchemaVersion, dump.SchemaVersion)
+	}
+	if len(got.Tables) != len(dumpTables) {
+		t.Errorf("exported %d tables, want %d", len(got.Tables), len(dumpTables))
+	}
+	for i := range dump.Tables {
+		if !reflect.DeepEqual(got.Tables[i], dump.Tables[i]) {
+			t.Errorf("table %s differs after the round trip:\n got %v\nwant %v", dump.Tables[i].Name, got.Tables[i].Rows, dump.Tables[i].Rows)
+		}
+	}
+}
+
+func TestImportOlderSchema(t *testing.T) {
+	ctx := context.Background()
+	src := openFixture(t, "v1.sql")
+	if _, err := MigrateTo(ctx, src, 3); err != nil {
+		t.Fatal(err)
+	}
+	dump, err := Export(ctx, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dump.SchemaVersion != 3 || len(dump.Tables) != 4 {
+		t.Fatalf("exported schema version %d with %d tables, want 3 with 4", dump.SchemaVersion, len(dump.Tables))
+	}
+
+	dst := openTestDB(t)
+	if _, err := Import(ctx, dst, roundTrip(t, dump)); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := SchemaVersion(ctx, dst); v != LatestSchemaVersion() {
+		t.Errorf("schema version after import = %d, want %d", v, LatestSchemaVersion())
+	}
+	// The later migrations fill in what version 3 lacked.
+	if got := count(t, dst, "SELECT COUNT(*) FROM overview WHERE period_start IS NULL"); got != 0 {
+		t.Errorf("%d periods without a start date", got)
+	}
+	if got := count(t, dst, "SELECT COUNT(*) FROM hashtags"); got != 3 {
+		t.Errorf("hashtags = %d, want 3 after merging the duplicate", got)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		dump  Dump
+		setup bool
+		want  string
+	}{
+		{"no schema version", Dump{}, false, "no schema version"},
+		{"not empty", Dump{SchemaVersion: 1}, true, "not empty"},
+		{"unknown table", Dump{SchemaVersion: 1, Tables: []DumpTable{{Name: "users"}}}, false, `unknown table "users"`},
+		{"table too new", Dump{SchemaVersion: 4, Tables: []DumpTable{{Name: "accounts"}}}, false, "does not exist at schema version 4"},
+		{"unknown column", Dump{SchemaVersion: 1, Tables: []DumpTable{{Name: "overview", Rows: []map[string]any{{"workspace": "Acme", "period": "2025-07", "fans": 1}}}}}, false, `unknown column "fans"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			if tt.setup {
+				if err := InitSchema(ctx, db); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err := Import(ctx, db, &tt.dump)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Import error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportTimestamp(t *testing.T) {
+	// Just after midnight on New Year's Day in Berlin is still the old year
+	// in UTC.
+	defer func(old func() time.Time) { now = old }(now)
+	now = func() time.Time {
+		return time.Date(2026, time.January, 1, 0, 30, 0, 999, time.FixedZone("CET", 3600))
+	}
+
+	dump, err := Export(context.Background(), openTestDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2025, time.December, 31, 23, 30, 0, 0, time.UTC); !dump.ExportedAt.Equal(want) || dump.ExportedAt.Location() != time.UTC {
+		t.Errorf("ExportedAt = %v, want %v", dump.ExportedAt, want)
+	}
+}
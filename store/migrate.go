@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migration upgrades the schema by one version. Databases created before
+// schema_version existed run every migration once, so each must be a no-op
+// if its change is already in place.
+type migration struct {
+	version int
+	name    string
+	apply   func(context.Context, *sql.DB) error
+}
+
+var migrations = []migration{
+	{1, "create tables", createTables},
+	{2, "add post metric and identity columns", migratePosts},
+	{3, "add country ranks", migrateCountries},
+	{4, "add unique keys for hashtags and posts", createUniqueIndexes},
+	{5, "add accounts table", createAccountsTable},
+	{6, "add AI response cache", createAICacheTable},
+	{7, "add period start and end dates", migrateOverviewDates},
+	{8, "exclude posts without date and link from the posts key", migratePostsKey},
+	{9, "add the social network to the posts key", migratePostsKey},
+}
+
+// LatestSchemaVersion is the schema version Migrate brings a database to.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// SchemaVersion returns the highest migration applied to db, or 0 if none
+// has been recorded yet.
+func SchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	if _, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_version (version INTEGER PRIMARY KEY, name TEXT, applied_at TEXT)"); err != nil {
+		return 0, err
+	}
+	var version int
+	err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version)
+	return version, err
+}
+
+// Migrate applies the pending migrations in order, records each in the
+// schema_version table, and returns the names of those it applied.
+func Migrate(ctx context.Context, db *sql.DB) ([]string, error) {
+	return MigrateTo(ctx, db, LatestSchemaVersion())
+}
+
+// MigrateTo is like Migrate but stops at schema version target.
+func MigrateTo(ctx context.Context, db *sql.DB, target int) ([]string, error) {
+	current, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema version: %w", err)
+	}
+	if latest := LatestSchemaVersion(); current > latest {
+		return nil, fmt.Errorf("database schema version %d is newer than the supported version %d", current, latest)
+	}
+
+	var applied []string
+	for _, m := range migrations {
+		if m.version <= current || m.version > target {
+			continue
+		}
+		if err := m.apply(ctx, db); err != nil {
+			return applied, fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+		if _, err := db.ExecContext(ctx, "INSERT INTO schema_version(version, name, applied_at) VALUES(?, ?, datetime('now'))", m.version, m.name); err != nil {
+			return applied, fmt.Errorf("error recording migration %d: %w", m.version, err)
+		}
+		applied = append(applied, m.name)
+	}
+	return applied, nil
+}
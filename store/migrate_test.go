@@ -0,0 +1,244 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/christophberger/publer-analytics-report/model"
+)
+
+// openTestDB returns an empty in-memory database. The pool is limited to
+// one connection because each connection to ":memory:" has its own
+// database.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// openFixture returns an in-memory database loaded from the SQL script in
+// testdata/name.
+func openFixture(t *testing.T, name string) *sql.DB {
+	t.Helper()
+	script, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := openTestDB(t)
+	if _, err := db.Exec(string(script)); err != nil {
+		t.Fatalf("loading %s: %v", name, err)
+	}
+	return db
+}
+
+func count(t *testing.T, db *sql.DB, query string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+func TestMigrateKeepsLegacyPosts(t *testing.T) {
+	ctx := context.Background()
+	db := openFixture(t, "v1.sql")
+
+	if _, err := Migrate(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	// The posts of the first release have no key columns, so not even the
+	// two identical ones of 2025-06 are merged.
+	for period, want := range map[string]int{"2025-06": 3, "2025-07": 2} {
+		if got := count(t, db, "SELECT COUNT(*) FROM posts WHERE period=?", period); got != want {
+			t.Errorf("posts of %s = %d, want %d", period, got, want)
+		}
+	}
+	// Repeated hashtags are merged, keeping the row inserted last.
+	if got := count(t, db, "SELECT COUNT(*) FROM hashtags WHERE period='2025-06'"); got != 2 {
+		t.Errorf("hashtags of 2025-06 = %d, want 2", got)
+	}
+	var score float64
+	if err := db.QueryRow("SELECT score FROM hashtags WHERE period='2025-06' AND hashtag='#sale'").Scan(&score); err != nil {
+		t.Fatal(err)
+	}
+	if score != 7.0 {
+		t.Errorf("score of #sale = %v, want 7 of the last row", score)
+	}
+}
+
+func TestMigrateDedupesKeyedPosts(t *testing.T) {
+	ctx := context.Background()
+	db := openFixture(t, "v1.sql")
+	if _, err := MigrateTo(ctx, db, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rows written by a release with the identity columns but before the
+	// unique index.
+	for _, p := range []struct{ network, account, date, link, text string }{
+		{"", "acme", "2025-07-01 10:00", "https://example.com/1", "first copy"},
+		{"", "acme", "2025-07-01 10:00", "https://example.com/1", "second copy"},
+		{"Instagram", "acme", "2025-07-01 10:00", "https://example.com/1", "on Instagram"},
+		{"", "acme", "", "", "no date or link"},
+		{"", "acme", "", "", "no date or link either"},
+	} {
+		if _, err := db.Exec("INSERT INTO posts(workspace, period, social_network, social_account, date, post_link, post_text) VALUES('Acme', '2025-08', NULLIF(?, ''), ?, ?, ?, ?)", p.network, p.account, p.date, p.link, p.text); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := Migrate(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	if got := count(t, db, "SELECT COUNT(*) FROM posts WHERE period='2025-08'"); got != 4 {
+		t.Errorf("posts of 2025-08 = %d, want 4", got)
+	}
+	if got := count(t, db, "SELECT COUNT(*) FROM posts WHERE post_text='second copy'"); got != 1 {
+		t.Errorf("the last of the duplicate posts was not kept")
+	}
+	if got := count(t, db, "SELECT COUNT(*) FROM posts WHERE period IN ('2025-06', '2025-07')"); got != 5 {
+		t.Errorf("legacy posts = %d, want 5", got)
+	}
+}
+
+func TestSaveMergesDuplicates(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	if err := InitSchema(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	overview := &model.OverviewData{WorkspaceName: "Acme", Followers: 100}
+	posts := []model.PostData{
+		{SocialAccount: "acme", Date: "2025-07-01 10:00", PostLink: "https://example.com/1", Reactions: 1},
+		{SocialAccount: "acme", Date: "2025-07-01 10:00", PostLink: "https://example.com/1", Reactions: 2},
+		// The same post on another network is a post of its own.
+		{SocialAccount: "acme", SocialNetwork: "Instagram", Date: "2025-07-01 10:00", PostLink: "https://example.com/1", Reactions: 5},
+		{SocialAccount: "acme", PostText: "no date or link", Reactions: 3},
+		{SocialAccount: "acme", PostText: "no date or link either", Reactions: 4},
+	}
+	hashtags := []model.HashtagData{
+		{Hashtag: "#sale", Score: 1},
+		{Hashtag: "#team", Score: 2},
+		{Hashtag: "#sale", Score: 3},
+	}
+	res, err := SavePeriod(ctx, db, "2025-07", overview, posts, hashtags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Posts != 4 || res.Hashtags != 2 {
+		t.Errorf("saved %d posts and %d hashtags, want 4 and 2", res.Posts, res.Hashtags)
+	}
+	if got := count(t, db, "SELECT reactions FROM posts WHERE post_link='https://example.com/1' AND social_network=''"); got != 2 {
+		t.Errorf("reactions of the duplicate post = %d, want 2 of the last row", got)
+	}
+	if got := count(t, db, "SELECT score FROM hashtags WHERE hashtag='#sale'"); got != 3 {
+		t.Errorf("score of the duplicate hashtag = %d, want 3 of the last row", got)
+	}
+
+	// Saving the period again replaces its rows instead of adding to them.
+	if res, err = SavePeriod(ctx, db, "2025-07", overview, posts, hashtags); err != nil {
+		t.Fatal(err)
+	}
+	if res.Posts != 4 || res.Hashtags != 2 {
+		t.Errorf("saved again %d posts and %d hashtags, want 4 and 2", res.Posts, res.Hashtags)
+	}
+}
+
+func TestMigrateV1(t *testing.T) {
+	ctx := context.Background()
+	db := openFixture(t, "v1.sql")
+
+	applied, err := Migrate(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(migrations) {
+		t.Errorf("applied %d migrations, want %d", len(applied), len(migrations))
+	}
+	if v, err := SchemaVersion(ctx, db); err != nil || v != LatestSchemaVersion() {
+		t.Errorf("schema version = %d, %v, want %d", v, err, LatestSchemaVersion())
+	}
+	if got := count(t, db, "SELECT COUNT(*) FROM schema_version"); got != len(migrations) {
+		t.Errorf("schema_version has %d rows, want %d", got, len(migrations))
+	}
+
+	tests := []struct {
+		table string
+		want  int
+	}{
+		{"overview", 2},
+		{"countries", 4},
+		{"posts", 5},
+		{"hashtags", 3},
+		{"accounts", 0},
+		{"ai_cache", 0},
+	}
+	for _, tt := range tests {
+		if got := count(t, db, "SELECT COUNT(*) FROM "+tt.table); got != tt.want {
+			t.Errorf("%s has %d rows, want %d", tt.table, got, tt.want)
+		}
+	}
+
+	// The migrations backfill the columns the first release lacked.
+	if got := count(t, db, "SELECT COUNT(*) FROM countries WHERE rank IS NULL"); got != 0 {
+		t.Errorf("%d countries without a rank", got)
+	}
+	if got := count(t, db, "SELECT rank FROM countries WHERE period='2025-07' AND country='Austria'"); got != 1 {
+		t.Errorf("rank of the tie broken by name = %d, want 1", got)
+	}
+	var start, end string
+	if err := db.QueryRow("SELECT period_start, period_end FROM overview WHERE period='2025-06'").Scan(&start, &end); err != nil {
+		t.Fatal(err)
+	}
+	if start != "2025-06-01" || end != "2025-06-30" {
+		t.Errorf("dates of 2025-06 = %s to %s, want 2025-06-01 to 2025-06-30", start, end)
+	}
+
+	// A second run finds nothing to do.
+	if applied, err := Migrate(ctx, db); err != nil || len(applied) != 0 {
+		t.Errorf("second Migrate applied %v, %v, want none", applied, err)
+	}
+}
+
+func TestMigrateTo(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	tests := []struct {
+		target  int
+		applied int
+	}{
+		{3, 3},
+		{3, 0},
+		{LatestSchemaVersion(), LatestSchemaVersion() - 3},
+	}
+	for _, tt := range tests {
+		applied, err := MigrateTo(ctx, db, tt.target)
+		if err != nil {
+			t.Fatalf("MigrateTo(%d): %v", tt.target, err)
+		}
+		if len(applied) != tt.applied {
+			t.Errorf("MigrateTo(%d) applied %d migrations, want %d", tt.target, len(applied), tt.applied)
+		}
+		if v, _ := SchemaVersion(ctx, db); v != tt.target {
+			t.Errorf("schema version after MigrateTo(%d) = %d", tt.target, v)
+		}
+	}
+
+	if _, err := db.Exec("INSERT INTO schema_version(version, name) VALUES(?, 'from the future')", LatestSchemaVersion()+1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Migrate(ctx, db); err == nil {
+		t.Error("Migrate of a newer schema succeeded, want an error")
+	}
+}
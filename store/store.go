@@ -0,0 +1,793 @@
+// Package store persists analytics data per workspace and period in SQLite.
+package store
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"github.com/christophberger/publer-analytics-report/model"
+)
+
+// Options tunes the SQLite connections. The zero values select defaults
+// that let concurrent runs on one database wait for each other instead of
+// failing with "database is locked".
+type Options struct {
+	// BusyTimeout is how long a statement waits for a lock that another
+	// connection holds. Zero selects DefaultBusyTimeout.
+	BusyTimeout time.Duration
+	// JournalMode is the SQLite journal mode: wal, delete, truncate,
+	// persist, memory, or off. Empty selects DefaultJournalMode.
+	JournalMode string
+}
+
+const (
+	DefaultBusyTimeout = 5 * time.Second
+	DefaultJournalMode = "wal"
+)
+
+var journalModes = []string{"wal", "delete", "truncate", "persist", "memory", "off"}
+
+// Open opens the database at path. Transactions take the write lock when
+// they begin, so that the busy timeout applies to them, too.
+func Open(path string, opts Options) (*sql.DB, error) {
+	timeout := cmp.Or(opts.BusyTimeout, DefaultBusyTimeout)
+	if timeout < 0 {
+		return nil, fmt.Errorf("busy timeout must not be negative, got %s", timeout)
+	}
+	mode := strings.ToLower(cmp.Or(opts.JournalMode, DefaultJournalMode))
+	if !slices.Contains(journalModes, mode) {
+		return nil, fmt.Errorf("unsupported journal mode %q, expected one of %s", opts.JournalMode, strings.Join(journalModes, ", "))
+	}
+
+	q := url.Values{}
+	q.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", timeout.Milliseconds()))
+	q.Add("_pragma", fmt.Sprintf("journal_mode(%s)", mode))
+	q.Set("_txlock", "immediate")
+
+	// The driver splits the parameters off at the first "?", so a path that
+	// holds one must be passed as an escaped file: URI.
+	dsn := path
+	if strings.ContainsRune(path, '?') {
+		dsn = "file:" + (&url.URL{Path: path}).EscapedPath()
+	}
+	return sql.Open("sqlite", dsn+"?"+q.Encode())
+}
+
+// maxBusyAttempts is how often a write that still finds the database
+// locked after the busy timeout is tried.
+const maxBusyAttempts = 3
+
+// retryBusy calls write until it succeeds, fails with an error other than
+// SQLITE_BUSY, or was tried maxBusyAttempts times.
+func retryBusy(ctx context.Context, write func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := write()
+		var serr *sqlite.Error
+		if err == nil || attempt >= maxBusyAttempts || !errors.As(err, &serr) || serr.Code()&0xff != sqlite3.SQLITE_BUSY {
+			return err
+		}
+		select {
+		case <-time.After(time.Duration(attempt) * 100 * time.Millisecond):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// InitSchema creates the tables of a new database, or brings an existing one
+// up to date, by applying the pending migrations.
+func InitSchema(ctx context.Context, db *sql.DB) error {
+	_, err := Migrate(ctx, db)
+	return err
+}
+
+func createTables(ctx context.Context, db *sql.DB) error {
+	stmts := []string{
+		"CREATE TABLE IF NOT EXISTS overview (workspace TEXT NOT NULL, period TEXT NOT NULL, followers INTEGER, reach INTEGER, reach_rate REAL, engagements INTEGER, engagement_rate REAL, PRIMARY KEY(workspace, period));",
+		"CREATE TABLE IF NOT EXISTS countries (workspace TEXT NOT NULL, period TEXT NOT NULL, country TEXT NOT NULL, users INTEGER, percentage REAL, rank INTEGER);",
+		"CREATE TABLE IF NOT EXISTS posts (workspace TEXT NOT NULL, period TEXT NOT NULL, post_text TEXT, post_type TEXT, reactions INTEGER, reach INTEGER, reach_rate REAL, comments INTEGER, shares INTEGER, engagement_rate REAL, link_clicks INTEGER, click_through_rate REAL, date TEXT, social_account TEXT, social_network TEXT, post_link TEXT);",
+		"CREATE TABLE IF NOT EXISTS hashtags (workspace TEXT NOT NULL, period TEXT NOT NULL, hashtag TEXT NOT NULL, score REAL, reach INTEGER, reactions INTEGER, comments INTEGER, shares INTEGER, video_views INTEGER);",
+	}
+	for _, s := range stmts {
+		if _, err := db.ExecContext(ctx, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createAccountsTable(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS accounts (workspace TEXT NOT NULL, period TEXT NOT NULL, account TEXT NOT NULL, network TEXT NOT NULL DEFAULT '', followers INTEGER, reach INTEGER, engagements INTEGER, engagement_rate REAL, PRIMARY KEY(workspace, period, account, network));")
+	return err
+}
+
+// uniqueIndexes identify a hashtag or post within a workspace and period.
+// Publer anonymizes some post links, so a post is keyed by account and
+// publishing time as well, and by network, because a post published on
+// several networks at once has the same account name and time on each. A
+// post with neither a date nor a link cannot be told apart from others, so
+// the posts key leaves it out.
+var uniqueIndexes = []struct{ name, table, columns, where string }{
+	{"hashtags_key", "hashtags", "workspace, period, hashtag", ""},
+	{"posts_key", "posts", "workspace, period, social_network, social_account, date, post_link", postsKeyWhere},
+}
+
+// postsKeyWhere is the condition of the partial posts_key index, which the
+// upsert of savePosts must repeat.
+const postsKeyWhere = "date <> '' OR post_link <> ''"
+
+func createUniqueIndexes(ctx context.Context, db *sql.DB) error {
+	for _, idx := range uniqueIndexes {
+		var exists int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type='index' AND name=?", idx.name).Scan(&exists); err != nil {
+			return err
+		}
+		if exists > 0 {
+			continue
+		}
+
+		// Databases written before the index existed may hold duplicates;
+		// keep the row inserted last, as the upserts do. Rows that lack a
+		// key column, such as the posts stored before migration 2, are
+		// distinct in the index, so GROUP BY must not merge them.
+		keyed := "(" + strings.ReplaceAll(idx.columns, ",", " IS NOT NULL AND") + " IS NOT NULL)"
+		index := fmt.Sprintf("CREATE UNIQUE INDEX %s ON %s(%s)", idx.name, idx.table, idx.columns)
+		if idx.where != "" {
+			keyed += " AND (" + idx.where + ")"
+			index += " WHERE " + idx.where
+		}
+		stmts := []string{
+			fmt.Sprintf("DELETE FROM %s WHERE %s AND rowid NOT IN (SELECT MAX(rowid) FROM %s WHERE %s GROUP BY %s)", idx.table, keyed, idx.table, keyed, idx.columns),
+			index,
+		}
+		for _, stmt := range stmts {
+			if _, err := db.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("error creating index %s: %w", idx.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// migratePostsKey recreates the posts key of databases migrated before its
+// latest change: before version 8, it was not a partial index and merged
+// the posts that have neither a date nor a link; before version 9, it
+// merged the same post on two networks. Posts written without a network
+// get an empty one, as savePosts stores, because the index would tell a
+// NULL apart from every other value.
+func migratePostsKey(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, "DROP INDEX IF EXISTS posts_key"); err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE posts SET social_network = '' WHERE social_network IS NULL AND ("+postsKeyWhere+")"); err != nil {
+		return err
+	}
+	return createUniqueIndexes(ctx, db)
+}
+
+type column struct{ name, typ string }
+
+func migratePosts(ctx context.Context, db *sql.DB) error {
+	_, err := addMissingColumns(ctx, db, "posts", []column{
+		{"reach", "INTEGER"},
+		{"reach_rate", "REAL"},
+		{"comments", "INTEGER"},
+		{"shares", "INTEGER"},
+		{"engagement_rate", "REAL"},
+		{"link_clicks", "INTEGER"},
+		{"click_through_rate", "REAL"},
+		{"date", "TEXT"},
+		{"social_account", "TEXT"},
+		{"social_network", "TEXT"},
+		{"post_link", "TEXT"},
+	})
+	return err
+}
+
+func migrateCountries(ctx context.Context, db *sql.DB) error {
+	added, err := addMissingColumns(ctx, db, "countries", []column{{"rank", "INTEGER"}})
+	if err != nil || len(added) == 0 {
+		return err
+	}
+
+	// Backfill the ranks of countries stored before the column existed,
+	// using the same order as SaveCountries.
+	_, err = db.ExecContext(ctx, `UPDATE countries SET rank = 1 + (
+		SELECT COUNT(*) FROM countries c
+		WHERE c.workspace = countries.workspace AND c.period = countries.period
+		AND (c.users > countries.users OR (c.users = countries.users AND c.country < countries.country)))`)
+	return err
+}
+
+// migrateOverviewDates adds the first and last day of each period to the
+// overview table. The periods stored before are whole months.
+func migrateOverviewDates(ctx context.Context, db *sql.DB) error {
+	if _, err := addMissingColumns(ctx, db, "overview", []column{{"period_start", "TEXT"}, {"period_end", "TEXT"}}); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, `UPDATE overview SET period_start = period || '-01', period_end = date(period || '-01', '+1 month', '-1 day')
+		WHERE period_start IS NULL OR period_end IS NULL`)
+	return err
+}
+
+func addMissingColumns(ctx context.Context, db *sql.DB, table string, cols []column) ([]string, error) {
+	existing, err := tableColumns(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+
+	var added []string
+	for _, c := range cols {
+		if existing[c.name] {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, c.name, c.typ)); err != nil {
+			return added, fmt.Errorf("error adding column %s.%s: %w", table, c.name, err)
+		}
+		added = append(added, c.name)
+	}
+	return added, nil
+}
+
+func tableColumns(ctx context.Context, db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols := map[string]bool{}
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return nil, err
+		}
+		cols[name] = true
+	}
+	return cols, rows.Err()
+}
+
+// SavePeriod replaces the stored data of one workspace and period, including
+// the overview's top countries and accounts, in a single transaction. Nil
+// posts or hashtags keep the stored ones, for a run that skipped their
+// export; an empty slice deletes them. The other months of a multi-month
+// overview are stored with their totals only.
+func SavePeriod(ctx context.Context, db *sql.DB, period string, overview *model.OverviewData, posts []model.PostData, hashtags []model.HashtagData) (SaveResult, error) {
+	var res SaveResult
+	err := retryBusy(ctx, func() error {
+		var err error
+		res, err = savePeriod(ctx, db, period, overview, posts, hashtags)
+		return err
+	})
+	return res, err
+}
+
+func savePeriod(ctx context.Context, db *sql.DB, period string, overview *model.OverviewData, posts []model.PostData, hashtags []model.HashtagData) (SaveResult, error) {
+	var res SaveResult
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback()
+
+	if err := saveOverview(ctx, tx, period, overview); err != nil {
+		return res, fmt.Errorf("error saving overview: %w", err)
+	}
+	for _, m := range overview.Months {
+		if m.Period == period {
+			continue
+		}
+		m.WorkspaceName = overview.WorkspaceName
+		if err := saveOverview(ctx, tx, m.Period, &m); err != nil {
+			return res, fmt.Errorf("error saving overview of %s: %w", m.Period, err)
+		}
+		res.Months++
+	}
+	if err := saveCountries(ctx, tx, period, overview.WorkspaceName, overview.TopCountries); err != nil {
+		return res, fmt.Errorf("error saving countries: %w", err)
+	}
+	if err := saveAccounts(ctx, tx, period, overview.WorkspaceName, overview.Accounts); err != nil {
+		return res, fmt.Errorf("error saving accounts: %w", err)
+	}
+	if posts != nil {
+		if err := savePosts(ctx, tx, period, overview.WorkspaceName, posts); err != nil {
+			return res, fmt.Errorf("error saving posts: %w", err)
+		}
+	}
+	if hashtags != nil {
+		if err := saveHashtags(ctx, tx, period, overview.WorkspaceName, hashtags); err != nil {
+			return res, fmt.Errorf("error saving hashtags: %w", err)
+		}
+	}
+
+	for _, c := range []struct {
+		table string
+		n     *int
+	}{{"countries", &res.Countries}, {"accounts", &res.Accounts}, {"posts", &res.Posts}, {"hashtags", &res.Hashtags}} {
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+c.table+" WHERE workspace=? AND period=?", overview.WorkspaceName, period).Scan(c.n); err != nil {
+			return res, fmt.Errorf("error counting %s: %w", c.table, err)
+		}
+	}
+
+	return res, tx.Commit()
+}
+
+// SaveResult holds the number of rows SavePeriod stored, after duplicate
+// rows in the export have been merged.
+type SaveResult struct {
+	Countries int
+	Accounts  int
+	Posts     int
+	Hashtags  int
+	// Months is the number of other months of a multi-month overview.
+	Months int
+}
+
+func saveOverview(ctx context.Context, tx *sql.Tx, period string, data *model.OverviewData) error {
+	start, end, err := periodDates(period, data)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO overview(workspace, period, period_start, period_end, followers, reach, reach_rate, engagements, engagement_rate) VALUES(?,?,?,?,?,?,?,?,?) ON CONFLICT(workspace, period) DO UPDATE SET period_start=excluded.period_start, period_end=excluded.period_end, followers=excluded.followers, reach=excluded.reach, reach_rate=excluded.reach_rate, engagements=excluded.engagements, engagement_rate=excluded.engagement_rate",
+		data.WorkspaceName, period, start, end, data.Followers, data.Reach, data.ReachRate, data.Engagements, data.EngagementRate,
+	)
+	return err
+}
+
+// dateLayout is the format of the period_start and period_end columns.
+const dateLayout = "2006-01-02"
+
+// periodDates returns the first and last day of data's period, which
+// default to those of the month period.
+func periodDates(period string, data *model.OverviewData) (start, end string, err error) {
+	if !data.PeriodStart.IsZero() && !data.PeriodEnd.IsZero() {
+		return data.PeriodStart.Format(dateLayout), data.PeriodEnd.Format(dateLayout), nil
+	}
+	t, err := time.Parse("2006-01", period)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid period %q, expected YYYY-MM", period)
+	}
+	return t.Format(dateLayout), t.AddDate(0, 1, -1).Format(dateLayout), nil
+}
+
+// rangeDates returns the first day of the month since and the last day of
+// the month until, for selecting the periods that start between them.
+func rangeDates(since, until string) (first, last string, err error) {
+	s, err := time.Parse("2006-01", since)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid month %q, expected YYYY-MM", since)
+	}
+	u, err := time.Parse("2006-01", until)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid month %q, expected YYYY-MM", until)
+	}
+	return s.Format(dateLayout), u.AddDate(0, 1, -1).Format(dateLayout), nil
+}
+
+// parseDate returns the time of a period_start or period_end value, or the
+// zero time if it is empty.
+func parseDate(s string) time.Time {
+	t, _ := time.Parse(dateLayout, s)
+	return t
+}
+
+func saveCountries(ctx context.Context, tx *sql.Tx, period string, workspace string, countries []model.CountryData) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM countries WHERE workspace=? AND period=?", workspace, period); err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO countries(workspace, period, country, users, percentage, rank) VALUES(?,?,?,?,?,?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for i, c := range rankCountries(countries) {
+		if _, err := stmt.ExecContext(ctx, workspace, period, c.Country, c.Users, c.Percentage, i+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func saveAccounts(ctx context.Context, tx *sql.Tx, period string, workspace string, accounts []model.AccountData) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM accounts WHERE workspace=? AND period=?", workspace, period); err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO accounts(workspace, period, account, network, followers, reach, engagements, engagement_rate) VALUES(?,?,?,?,?,?,?,?) ON CONFLICT(workspace, period, account, network) DO UPDATE SET followers=excluded.followers, reach=excluded.reach, engagements=excluded.engagements, engagement_rate=excluded.engagement_rate")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, a := range accounts {
+		if _, err := stmt.ExecContext(ctx, workspace, period, a.Account, a.Network, a.Followers, a.Reach, a.Engagements, a.EngagementRate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rankCountries returns a copy of countries ordered by descending users,
+// with ties broken by country name.
+func rankCountries(countries []model.CountryData) []model.CountryData {
+	ranked := slices.Clone(countries)
+	slices.SortStableFunc(ranked, func(a, b model.CountryData) int {
+		if a.Users != b.Users {
+			return cmp.Compare(b.Users, a.Users)
+		}
+		return strings.Compare(a.Country, b.Country)
+	})
+	return ranked
+}
+
+func savePosts(ctx context.Context, tx *sql.Tx, period string, workspace string, posts []model.PostData) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM posts WHERE workspace=? AND period=?", workspace, period); err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO posts(workspace, period, post_text, post_type, reactions, reach, reach_rate, comments, shares, engagement_rate, link_clicks, click_through_rate, date, social_account, social_network, post_link) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?) ON CONFLICT(workspace, period, social_network, social_account, date, post_link) WHERE "+postsKeyWhere+" DO UPDATE SET post_text=excluded.post_text, post_type=excluded.post_type, reactions=excluded.reactions, reach=excluded.reach, reach_rate=excluded.reach_rate, comments=excluded.comments, shares=excluded.shares, engagement_rate=excluded.engagement_rate, link_clicks=excluded.link_clicks, click_through_rate=excluded.click_through_rate")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, p := range posts {
+		if _, err := stmt.ExecContext(ctx, workspace, period, p.PostText, p.PostType, p.Reactions, p.Reach, p.ReachRate, p.Comments, p.Shares, p.EngagementRate, p.LinkClicks, p.ClickThroughRate, p.Date, p.SocialAccount, p.SocialNetwork, p.PostLink); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func saveHashtags(ctx context.Context, tx *sql.Tx, period string, workspace string, hashtags []model.HashtagData) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM hashtags WHERE workspace=? AND period=?", workspace, period); err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO hashtags(workspace, period, hashtag, score, reach, reactions, comments, shares, video_views) VALUES(?,?,?,?,?,?,?,?,?) ON CONFLICT(workspace, period, hashtag) DO UPDATE SET score=excluded.score, reach=excluded.reach, reactions=excluded.reactions, comments=excluded.comments, shares=excluded.shares, video_views=excluded.video_views")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, h := range hashtags {
+		if _, err := stmt.ExecContext(ctx, workspace, period, h.Hashtag, h.Score, h.Reach, h.Reactions, h.Comments, h.Shares, h.VideoViews); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetHashtagChanges joins the hashtags of period with those of prev and
+// returns the score changes, largest increase first. Hashtags used in only
+// one of the two periods count as zero in the other.
+func GetHashtagChanges(ctx context.Context, db *sql.DB, workspace, period, prev string) ([]model.HashtagChange, error) {
+	rows, err := db.QueryContext(ctx, `SELECT hashtag, cur, prev FROM (
+		SELECT c.hashtag AS hashtag, c.score AS cur, p.score AS prev FROM hashtags c
+		LEFT JOIN hashtags p ON p.workspace = c.workspace AND p.hashtag = c.hashtag AND p.period = ?
+		WHERE c.workspace = ? AND c.period = ?
+		UNION ALL
+		SELECT p.hashtag, NULL, p.score FROM hashtags p
+		WHERE p.workspace = ? AND p.period = ? AND NOT EXISTS (
+			SELECT 1 FROM hashtags c WHERE c.workspace = p.workspace AND c.hashtag = p.hashtag AND c.period = ?)
+	) ORDER BY COALESCE(cur, 0) - COALESCE(prev, 0) DESC, hashtag`,
+		prev, workspace, period, workspace, prev, period)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []model.HashtagChange
+	for rows.Next() {
+		var c model.HashtagChange
+		var cur, before sql.NullFloat64
+		if err := rows.Scan(&c.Hashtag, &cur, &before); err != nil {
+			return nil, err
+		}
+		c.Score, c.PreviousScore = cur.Float64, before.Float64
+		c.Change = c.Score - c.PreviousScore
+		c.New, c.Dropped = !before.Valid, !cur.Valid
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// GetOverview returns the stored overview of workspace for the period
+// YYYY-MM, or nil if that period is not stored.
+func GetOverview(ctx context.Context, db *sql.DB, workspace, period string) (*model.OverviewData, error) {
+	row := db.QueryRowContext(ctx, "SELECT followers, reach, reach_rate, engagements, engagement_rate FROM overview WHERE workspace=? AND period=?", workspace, period)
+	var followers, reach, engagements int
+	var reachRate, engagementRate float64
+	err := row.Scan(&followers, &reach, &reachRate, &engagements, &engagementRate)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &model.OverviewData{WorkspaceName: workspace, Period: period, Followers: followers, Reach: reach, ReachRate: reachRate, Engagements: engagements, EngagementRate: engagementRate}, nil
+}
+
+// GetOverviewHistory returns up to n stored periods of workspace up to and
+// including until, oldest first. The limit keeps the latest ones.
+func GetOverviewHistory(ctx context.Context, db *sql.DB, workspace, until string, n int) ([]model.OverviewData, error) {
+	rows, err := db.QueryContext(ctx, "SELECT period, COALESCE(period_start, ''), COALESCE(period_end, ''), followers, reach, reach_rate, engagements, engagement_rate FROM overview WHERE workspace=? AND period<=? ORDER BY period DESC LIMIT ?", workspace, until, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []model.OverviewData
+	for rows.Next() {
+		o := model.OverviewData{WorkspaceName: workspace}
+		var start, end string
+		if err := rows.Scan(&o.Period, &start, &end, &o.Followers, &o.Reach, &o.ReachRate, &o.Engagements, &o.EngagementRate); err != nil {
+			return nil, err
+		}
+		o.PeriodStart, o.PeriodEnd = parseDate(start), parseDate(end)
+		history = append(history, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(history)
+	return history, nil
+}
+
+// GetCountriesHistory returns the stored country rankings of workspace for
+// every period up to and including until, oldest first.
+func GetCountriesHistory(ctx context.Context, db *sql.DB, workspace, until string) ([]model.CountryRanking, error) {
+	rows, err := db.QueryContext(ctx, "SELECT period, country, users, percentage, rank FROM countries WHERE workspace=? AND period<=? ORDER BY period, rank", workspace, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []model.CountryRanking
+	for rows.Next() {
+		var period string
+		var c model.CountryData
+		if err := rows.Scan(&period, &c.Country, &c.Users, &c.Percentage, &c.Rank); err != nil {
+			return nil, err
+		}
+		if len(history) == 0 || history[len(history)-1].Period != period {
+			history = append(history, model.CountryRanking{Period: period})
+		}
+		last := &history[len(history)-1]
+		last.Countries = append(last.Countries, c)
+	}
+	return history, rows.Err()
+}
+
+// PeriodSummary counts the rows stored for one workspace and period.
+type PeriodSummary struct {
+	Workspace string
+	Period    string
+	Posts     int
+	Hashtags  int
+	Countries int
+}
+
+// ListPeriods returns the stored periods ordered by workspace and period,
+// optionally limited to one workspace.
+func ListPeriods(ctx context.Context, db *sql.DB, workspace string) ([]PeriodSummary, error) {
+	rows, err := db.QueryContext(ctx, `SELECT o.workspace, o.period,
+		(SELECT COUNT(*) FROM posts p WHERE p.workspace = o.workspace AND p.period = o.period),
+		(SELECT COUNT(*) FROM hashtags h WHERE h.workspace = o.workspace AND h.period = o.period),
+		(SELECT COUNT(*) FROM countries c WHERE c.workspace = o.workspace AND c.period = o.period)
+		FROM overview o WHERE ? = '' OR o.workspace = ? ORDER BY o.workspace, o.period`, workspace, workspace)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var periods []PeriodSummary
+	for rows.Next() {
+		var p PeriodSummary
+		if err := rows.Scan(&p.Workspace, &p.Period, &p.Posts, &p.Hashtags, &p.Countries); err != nil {
+			return nil, err
+		}
+		periods = append(periods, p)
+	}
+	return periods, rows.Err()
+}
+
+// ListWorkspaces returns the names of the workspaces with stored periods,
+// sorted.
+func ListWorkspaces(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT DISTINCT workspace FROM overview ORDER BY workspace")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var workspaces []string
+	for rows.Next() {
+		var w string
+		if err := rows.Scan(&w); err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, w)
+	}
+	return workspaces, rows.Err()
+}
+
+// GetOverviewRange returns the stored periods of workspace that start in
+// the months since to until, both in the form YYYY-MM, oldest first.
+func GetOverviewRange(ctx context.Context, db *sql.DB, workspace, since, until string) ([]model.OverviewData, error) {
+	first, last, err := rangeDates(since, until)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT period, period_start, period_end, followers, reach, reach_rate, engagements, engagement_rate FROM overview WHERE workspace=? AND period_start>=? AND period_start<=? ORDER BY period_start", workspace, first, last)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overviews []model.OverviewData
+	for rows.Next() {
+		o := model.OverviewData{WorkspaceName: workspace}
+		var start, end string
+		if err := rows.Scan(&o.Period, &start, &end, &o.Followers, &o.Reach, &o.ReachRate, &o.Engagements, &o.EngagementRate); err != nil {
+			return nil, err
+		}
+		o.PeriodStart, o.PeriodEnd = parseDate(start), parseDate(end)
+		overviews = append(overviews, o)
+	}
+	return overviews, rows.Err()
+}
+
+// periodsInRange selects the periods of GetOverviewRange in the posts and
+// hashtags queries. Its parameters are the workspace and the dates from
+// rangeDates.
+const periodsInRange = "period IN (SELECT period FROM overview WHERE workspace=? AND period_start>=? AND period_start<=?)"
+
+// GetPostsRange returns the posts of the periods of workspace that start in
+// the months since to until, both included, ordered by period and date.
+func GetPostsRange(ctx context.Context, db *sql.DB, workspace, since, until string) ([]model.PostData, error) {
+	first, last, err := rangeDates(since, until)
+	if err != nil {
+		return nil, err
+	}
+	return queryPosts(ctx, db, "workspace=? AND "+periodsInRange+" ORDER BY period, date", workspace, workspace, first, last)
+}
+
+// queryPosts returns the posts that match where, which may end in an
+// ORDER BY clause.
+func queryPosts(ctx context.Context, db *sql.DB, where string, args ...any) ([]model.PostData, error) {
+	rows, err := db.QueryContext(ctx, "SELECT COALESCE(date, ''), COALESCE(social_account, ''), COALESCE(social_network, ''), COALESCE(post_link, ''), COALESCE(post_text, ''), COALESCE(post_type, ''), COALESCE(reach, 0), COALESCE(reach_rate, 0), COALESCE(reactions, 0), COALESCE(comments, 0), COALESCE(shares, 0), COALESCE(engagement_rate, 0), COALESCE(link_clicks, 0), COALESCE(click_through_rate, 0) FROM posts WHERE "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []model.PostData
+	for rows.Next() {
+		var p model.PostData
+		if err := rows.Scan(&p.Date, &p.SocialAccount, &p.SocialNetwork, &p.PostLink, &p.PostText, &p.PostType, &p.Reach, &p.ReachRate, &p.Reactions, &p.Comments, &p.Shares, &p.EngagementRate, &p.LinkClicks, &p.ClickThroughRate); err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+// GetHashtagTotals sums each hashtag's score and interactions over the
+// periods from since to until.
+func GetHashtagTotals(ctx context.Context, db *sql.DB, workspace, since, until string) ([]model.HashtagData, error) {
+	first, last, err := rangeDates(since, until)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT hashtag, SUM(COALESCE(score, 0)), SUM(COALESCE(reach, 0)), SUM(COALESCE(reactions, 0)), SUM(COALESCE(comments, 0)), SUM(COALESCE(shares, 0)), SUM(COALESCE(video_views, 0)) FROM hashtags WHERE workspace=? AND "+periodsInRange+" GROUP BY hashtag ORDER BY hashtag", workspace, workspace, first, last)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashtags []model.HashtagData
+	for rows.Next() {
+		var h model.HashtagData
+		if err := rows.Scan(&h.Hashtag, &h.Score, &h.Reach, &h.Reactions, &h.Comments, &h.Shares, &h.VideoViews); err != nil {
+			return nil, err
+		}
+		hashtags = append(hashtags, h)
+	}
+	return hashtags, rows.Err()
+}
+
+// GetPeriod returns the stored data of one workspace and period: the
+// overview with its countries and accounts, the posts, and the hashtags.
+// The overview is nil if the period is not stored.
+func GetPeriod(ctx context.Context, db *sql.DB, workspace, period string) (*model.OverviewData, []model.PostData, []model.HashtagData, error) {
+	o := &model.OverviewData{WorkspaceName: workspace, Period: period}
+	var start, end string
+	err := db.QueryRowContext(ctx, "SELECT COALESCE(period_start, ''), COALESCE(period_end, ''), followers, reach, reach_rate, engagements, engagement_rate FROM overview WHERE workspace=? AND period=?", workspace, period).
+		Scan(&start, &end, &o.Followers, &o.Reach, &o.ReachRate, &o.Engagements, &o.EngagementRate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error reading overview: %w", err)
+	}
+	o.PeriodStart, o.PeriodEnd = parseDate(start), parseDate(end)
+
+	if o.TopCountries, err = getCountries(ctx, db, workspace, period); err != nil {
+		return nil, nil, nil, fmt.Errorf("error reading countries: %w", err)
+	}
+	if o.Accounts, err = getAccounts(ctx, db, workspace, period); err != nil {
+		return nil, nil, nil, fmt.Errorf("error reading accounts: %w", err)
+	}
+	posts, err := queryPosts(ctx, db, "workspace=? AND period=? ORDER BY rowid", workspace, period)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error reading posts: %w", err)
+	}
+	hashtags, err := getHashtags(ctx, db, workspace, period)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error reading hashtags: %w", err)
+	}
+	return o, posts, hashtags, nil
+}
+
+func getCountries(ctx context.Context, db *sql.DB, workspace, period string) ([]model.CountryData, error) {
+	rows, err := db.QueryContext(ctx, "SELECT country, COALESCE(users, 0), COALESCE(percentage, 0), COALESCE(rank, 0) FROM countries WHERE workspace=? AND period=? ORDER BY rank", workspace, period)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var countries []model.CountryData
+	for rows.Next() {
+		var c model.CountryData
+		if err := rows.Scan(&c.Country, &c.Users, &c.Percentage, &c.Rank); err != nil {
+			return nil, err
+		}
+		countries = append(countries, c)
+	}
+	return countries, rows.Err()
+}
+
+func getAccounts(ctx context.Context, db *sql.DB, workspace, period string) ([]model.AccountData, error) {
+	rows, err := db.QueryContext(ctx, "SELECT account, network, COALESCE(followers, 0), COALESCE(reach, 0), COALESCE(engagements, 0), COALESCE(engagement_rate, 0) FROM accounts WHERE workspace=? AND period=? ORDER BY rowid", workspace, period)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []model.AccountData
+	for rows.Next() {
+		var a model.AccountData
+		if err := rows.Scan(&a.Account, &a.Network, &a.Followers, &a.Reach, &a.Engagements, &a.EngagementRate); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+func getHashtags(ctx context.Context, db *sql.DB, workspace, period string) ([]model.HashtagData, error) {
+	rows, err := db.QueryContext(ctx, "SELECT hashtag, COALESCE(score, 0), COALESCE(reach, 0), COALESCE(reactions, 0), COALESCE(comments, 0), COALESCE(shares, 0), COALESCE(video_views, 0) FROM hashtags WHERE workspace=? AND period=? ORDER BY rowid", workspace, period)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashtags []model.HashtagData
+	for rows.Next() {
+		var h model.HashtagData
+		if err := rows.Scan(&h.Hashtag, &h.Score, &h.Reach, &h.Reactions, &h.Comments, &h.Shares, &h.VideoViews); err != nil {
+			return nil, err
+		}
+		hashtags = append(hashtags, h)
+	}
+	return hashtags, rows.Err()
+}
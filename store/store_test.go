@@ -0,0 +1,227 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/christophberger/publer-analytics-report/model"
+)
+
+func TestSavePeriodRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	if err := InitSchema(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	overview := &model.OverviewData{
+		WorkspaceName:  "Acme",
+		Followers:      4750,
+		Reach:          507,
+		ReachRate:      3.59,
+		Engagements:    105,
+		EngagementRate: 20.71,
+		TopCountries: []model.CountryData{
+			{Country: "Switzerland", Users: 446, Percentage: 66.8},
+			{Country: "Germany", Users: 221, Percentage: 33.2},
+		},
+		Accounts: []model.AccountData{
+			{Account: "Acme", Network: "Linkedin", Followers: 4000, Reach: 400, Engagements: 90, EngagementRate: 22.5},
+		},
+	}
+	posts := []model.PostData{
+		{Date: "2025-07-31 10:45", SocialAccount: "Acme", SocialNetwork: "Linkedin", PostLink: "https://example.com/1", PostText: "Sign up", PostType: "Link", Reach: 34, ReachRate: 2.65, Reactions: 1, EngagementRate: 5.88},
+		{Date: "2025-07-30 09:00", SocialAccount: "Acme", SocialNetwork: "Linkedin", PostLink: "https://example.com/2", PostText: "Grüße 👋", PostType: "Status", Reach: 120, Reactions: 9, Comments: 2, Shares: 1, LinkClicks: 4, ClickThroughRate: 3.3},
+	}
+	hashtags := []model.HashtagData{
+		{Hashtag: "#ai", Score: 4.76, Reactions: 5},
+		{Hashtag: "#go", Score: 10.48, Reach: 10, Reactions: 7, Comments: 4, Shares: 1, VideoViews: 30},
+	}
+
+	res, err := SavePeriod(ctx, db, "2025-07", overview, posts, hashtags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (SaveResult{Countries: 2, Accounts: 1, Posts: 2, Hashtags: 2}); res != want {
+		t.Errorf("SavePeriod = %+v, want %+v", res, want)
+	}
+
+	gotOverview, gotPosts, gotHashtags, err := GetPeriod(ctx, db, "Acme", "2025-07")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := *overview
+	want.Period = "2025-07"
+	want.PeriodStart = time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	want.PeriodEnd = time.Date(2025, 7, 31, 0, 0, 0, 0, time.UTC)
+	want.TopCountries = []model.CountryData{
+		{Country: "Switzerland", Users: 446, Percentage: 66.8, Rank: 1},
+		{Country: "Germany", Users: 221, Percentage: 33.2, Rank: 2},
+	}
+	if !reflect.DeepEqual(*gotOverview, want) {
+		t.Errorf("overview:\n got %+v\nwant %+v", *gotOverview, want)
+	}
+	if !reflect.DeepEqual(gotPosts, posts) {
+		t.Errorf("posts:\n got %+v\nwant %+v", gotPosts, posts)
+	}
+	if !reflect.DeepEqual(gotHashtags, hashtags) {
+		t.Errorf("hashtags:\n got %+v\nwant %+v", gotHashtags, hashtags)
+	}
+
+	prev, err := GetOverview(ctx, db, "Acme", "2025-07")
+	if err != nil || prev == nil || prev.Followers != 4750 {
+		t.Errorf("GetOverview = %+v, %v", prev, err)
+	}
+	if prev, err := GetOverview(ctx, db, "Acme", "2025-06"); prev != nil || err != nil {
+		t.Errorf("GetOverview of a missing period = %+v, %v, want nil", prev, err)
+	}
+	if o, _, _, err := GetPeriod(ctx, db, "Other", "2025-07"); o != nil || err != nil {
+		t.Errorf("GetPeriod of a missing workspace = %+v, %v, want nil", o, err)
+	}
+}
+
+func TestSavePeriodKeepsNilSlices(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	if err := InitSchema(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	overview := &model.OverviewData{WorkspaceName: "Acme"}
+	posts := []model.PostData{{SocialAccount: "Acme", Date: "2025-07-01 10:00", PostText: "hello"}}
+	hashtags := []model.HashtagData{{Hashtag: "#go"}}
+	if _, err := SavePeriod(ctx, db, "2025-07", overview, posts, hashtags); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		posts    []model.PostData
+		hashtags []model.HashtagData
+		want     SaveResult
+	}{
+		{"nil keeps", nil, nil, SaveResult{Posts: 1, Hashtags: 1}},
+		{"empty deletes", []model.PostData{}, []model.HashtagData{}, SaveResult{}},
+	}
+	for _, tt := range tests {
+		res, err := SavePeriod(ctx, db, "2025-07", overview, tt.posts, tt.hashtags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res != tt.want {
+			t.Errorf("%s: SavePeriod = %+v, want %+v", tt.name, res, tt.want)
+		}
+	}
+}
+
+func TestOpenOptions(t *testing.T) {
+	tests := []struct {
+		opts Options
+		ok   bool
+	}{
+		{Options{}, true},
+		{Options{BusyTimeout: time.Second, JournalMode: "DELETE"}, true},
+		{Options{BusyTimeout: -time.Second}, false},
+		{Options{JournalMode: "fast"}, false},
+	}
+	for _, tt := range tests {
+		db, err := Open(t.TempDir()+"/test.db", tt.opts)
+		if (err == nil) != tt.ok {
+			t.Errorf("Open(%+v) error = %v, want ok %v", tt.opts, err, tt.ok)
+		}
+		if db != nil {
+			db.Close()
+		}
+	}
+}
+
+func TestRankCountries(t *testing.T) {
+	tests := []struct {
+		name      string
+		countries []model.CountryData
+		want      []string
+	}{
+		{"by users", []model.CountryData{{Country: "Germany", Users: 221}, {Country: "Switzerland", Users: 446}}, []string{"Switzerland", "Germany"}},
+		{"ties by name", []model.CountryData{{Country: "Luxembourg", Users: 54}, {Country: "Austria", Users: 54}, {Country: "France", Users: 54}}, []string{"Austria", "France", "Luxembourg"}},
+		{"ties below the top", []model.CountryData{{Country: "Spain", Users: 5}, {Country: "Italy", Users: 5}, {Country: "Germany", Users: 9}}, []string{"Germany", "Italy", "Spain"}},
+		{"none", nil, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, c := range rankCountries(tt.countries) {
+			got = append(got, c.Country)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: rankCountries = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGetCountriesHistory(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	if err := InitSchema(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []struct {
+		workspace, period string
+		countries         []model.CountryData
+	}{
+		{"Acme", "2025-06", []model.CountryData{{Country: "Germany", Users: 200}, {Country: "Austria", Users: 200}, {Country: "Switzerland", Users: 400}}},
+		{"Acme", "2025-07", []model.CountryData{{Country: "Germany", Users: 221}, {Country: "Switzerland", Users: 446}}},
+		{"Acme", "2025-08", []model.CountryData{{Country: "France", Users: 10}}},
+		{"Other", "2025-07", []model.CountryData{{Country: "Spain", Users: 5}}},
+	} {
+		overview := &model.OverviewData{WorkspaceName: p.workspace, TopCountries: p.countries}
+		if _, err := SavePeriod(ctx, db, p.period, overview, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := GetCountriesHistory(ctx, db, "Acme", "2025-07")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.CountryRanking{
+		{Period: "2025-06", Countries: []model.CountryData{
+			{Country: "Switzerland", Users: 400, Rank: 1},
+			{Country: "Austria", Users: 200, Rank: 2},
+			{Country: "Germany", Users: 200, Rank: 3},
+		}},
+		{Period: "2025-07", Countries: []model.CountryData{
+			{Country: "Switzerland", Users: 446, Rank: 1},
+			{Country: "Germany", Users: 221, Rank: 2},
+		}},
+	}
+	if !reflect.DeepEqual(history, want) {
+		t.Errorf("GetCountriesHistory = %+v, want %+v", history, want)
+	}
+
+	if history, err := GetCountriesHistory(ctx, db, "Nobody", "2025-07"); err != nil || history != nil {
+		t.Errorf("GetCountriesHistory of an unknown workspace = %+v, %v, want none", history, err)
+	}
+}
+
+func TestSavePostsReplacesPeriod(t *testing.T) {
+	db := openTestDB(t)
+	if err := InitSchema(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	posts := []model.PostData{
+		{Date: "2025-07-31 10:45", SocialAccount: "Acme", SocialNetwork: "Linkedin", PostLink: "https://example.com/1", PostText: "Sign up", PostType: "Link", Reach: 34, Reactions: 1},
+		{Date: "2025-07-30 09:00", SocialAccount: "Acme", SocialNetwork: "Linkedin", PostText: "Grüße 👋", PostType: "Status", Reach: 120, Reactions: 9, Comments: 2},
+	}
+	for range 2 {
+		if _, err := SavePeriod(context.Background(), db, "2025-07", &model.OverviewData{WorkspaceName: "Acme"}, posts, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var n, reactions int
+	if err := db.QueryRow("SELECT COUNT(*), SUM(reactions) FROM posts WHERE workspace='Acme' AND period='2025-07'").Scan(&n, &reactions); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || reactions != 10 {
+		t.Errorf("stored %d posts with %d reactions, want 2 with 10", n, reactions)
+	}
+}
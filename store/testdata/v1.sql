@@ -0,0 +1,26 @@
+-- A database written before schema versioning, with the tables of the
+-- first release: posts have neither metrics nor identity columns, countries
+-- have no rank, and repeated hashtags were stored twice.
+CREATE TABLE overview (workspace TEXT NOT NULL, period TEXT NOT NULL, followers INTEGER, reach INTEGER, reach_rate REAL, engagements INTEGER, engagement_rate REAL, PRIMARY KEY(workspace, period));
+CREATE TABLE countries (workspace TEXT NOT NULL, period TEXT NOT NULL, country TEXT NOT NULL, users INTEGER, percentage REAL);
+CREATE TABLE posts (workspace TEXT NOT NULL, period TEXT NOT NULL, post_text TEXT, post_type TEXT, reactions INTEGER);
+CREATE TABLE hashtags (workspace TEXT NOT NULL, period TEXT NOT NULL, hashtag TEXT NOT NULL, score REAL, reach INTEGER, reactions INTEGER, comments INTEGER, shares INTEGER, video_views INTEGER);
+
+INSERT INTO overview VALUES ('Acme', '2025-06', 1200, 5400, 4.5, 320, 5.9);
+INSERT INTO overview VALUES ('Acme', '2025-07', 1250, 6100, 4.9, 410, 6.7);
+
+INSERT INTO countries VALUES ('Acme', '2025-06', 'Germany', 300, 60.0);
+INSERT INTO countries VALUES ('Acme', '2025-06', 'Austria', 200, 40.0);
+INSERT INTO countries VALUES ('Acme', '2025-07', 'Austria', 250, 50.0);
+INSERT INTO countries VALUES ('Acme', '2025-07', 'Germany', 250, 50.0);
+
+INSERT INTO posts VALUES ('Acme', '2025-06', 'Summer sale', 'Photo', 40);
+INSERT INTO posts VALUES ('Acme', '2025-06', 'Team outing', 'Video', 25);
+INSERT INTO posts VALUES ('Acme', '2025-06', 'Summer sale', 'Photo', 40);
+INSERT INTO posts VALUES ('Acme', '2025-07', 'New product', 'Carousel', 61);
+INSERT INTO posts VALUES ('Acme', '2025-07', 'Behind the scenes', 'Reel', 17);
+
+INSERT INTO hashtags VALUES ('Acme', '2025-06', '#sale', 6.5, 900, 50, 4, 2, 0);
+INSERT INTO hashtags VALUES ('Acme', '2025-06', '#team', 3.0, 300, 20, 1, 0, 120);
+INSERT INTO hashtags VALUES ('Acme', '2025-06', '#sale', 7.0, 950, 55, 5, 3, 0);
+INSERT INTO hashtags VALUES ('Acme', '2025-07', '#launch', 8.25, 1400, 70, 9, 6, 300);
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/christophberger/publer-analytics-report/report"
+)
+
+func runStoredReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	dbPath := fs.String("db", "", "path to the SQLite database (default $PUBLER_DB or analytics.db)")
+	configFile := fs.String("config", report.DefaultConfigFile, "path to the configuration file (default searches ./config.yaml, then the user config directory)")
+	workspace := fs.String("workspace", "", "workspace to report on (default the only one in the database)")
+	period := fs.String("period", "", "stored period to report on, as YYYY-MM (default the latest one)")
+	var output string
+	fs.StringVar(&output, "o", "", "output file or directory for the report (shorthand)")
+	fs.StringVar(&output, "output", "", "output file or directory for the report")
+	format := fs.String("format", "md", "comma-separated report formats: md, html, json, csv")
+	appendMD := fs.Bool("append", false, "insert or replace the month's section in the workspace's master Markdown report instead of writing a file per month")
+	templateFile := fs.String("template", "", "custom text/template file for the Markdown report")
+	top := fs.Int("top", 5, "number of top posts, hashtags, and countries to show (0 or less shows all)")
+	decimals := fs.Int("decimals", report.DefaultDecimals, "number of decimal places of percentages and averages in the report")
+	history := fs.Int("history", 6, "number of stored months to show in the trend table (0 hides it)")
+	yoy := fs.Bool("yoy", false, "also compare against the same month of the previous year")
+	postTypes := fs.String("post-types", report.DefaultPostTypes, `comma-separated post types to rank as top posts, or "all"`)
+	network := fs.String("network", "", "only rank posts from this social network, such as LinkedIn")
+	rankBy := fs.String("rank-by", report.DefaultRankBy, "metric to rank the top posts by: reactions, engagements, reach, or clicks")
+	hashtagRankBy := fs.String("hashtag-rank-by", report.DefaultHashtagRankBy, "metric to rank the top hashtags by: score, reach, or engagement")
+	rateBasis := fs.String("engagement-rate-basis", "", "recompute the engagement rate as engagements per reach or per followers (default the config's engagement_rate_basis, or Publer's rate, which is per reach)")
+	redact := fs.Bool("redact", false, `replace the text of each top post with a placeholder such as "[Post #1]" and drop its link, in the report and the AI prompts`)
+	lang := fs.String("lang", "", "language of the AI texts, as a tag such as de or a name such as German (default the config's language, or English)")
+	noAI := fs.Bool("no-ai", false, "skip the AI insights and next steps")
+	noCache := fs.Bool("no-cache", false, "send every prompt to the API instead of reusing the cached response to an identical one")
+	stream := fs.Bool("stream", false, "stream the AI responses and echo them to stderr as they arrive (OpenAI-compatible APIs only)")
+	dumpPrompts := fs.Bool("dump-prompts", false, "print the rendered AI prompts to stderr before they are sent, even with --no-ai")
+	failOnAIError := fs.Bool("fail-on-ai-error", false, "fail without writing a report if the AI insights or next steps cannot be generated")
+	openReport := fs.Bool("open", false, "open the report when it is written, in $EDITOR or with the system's default application (HTML in the browser); ignored with --quiet")
+	verbose := fs.Bool("verbose", false, "log periods and API timing to stderr")
+	quiet := fs.Bool("quiet", false, "print nothing but errors")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s report [flags]\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	setupLogging(*verbose, *quiet)
+
+	formats, err := report.ParseFormats(*format)
+	if err != nil {
+		return usageError{fmt.Errorf("error parsing formats: %w", err)}
+	}
+	by, err := report.ParseRankBy(*rankBy)
+	if err != nil {
+		return usageError{fmt.Errorf("error parsing ranking: %w", err)}
+	}
+	hashtagBy, err := report.ParseHashtagRankBy(*hashtagRankBy)
+	if err != nil {
+		return usageError{fmt.Errorf("error parsing hashtag ranking: %w", err)}
+	}
+	basis, err := report.ParseRateBasis(*rateBasis)
+	if err != nil {
+		return usageError{fmt.Errorf("error parsing engagement rate basis: %w", err)}
+	}
+	language, err := report.ParseLanguage(*lang)
+	if err != nil {
+		return usageError{fmt.Errorf("error parsing language: %w", err)}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	opts := report.Options{
+		Period:        *period,
+		Output:        output,
+		Formats:       formats,
+		TemplateFile:  *templateFile,
+		Workspace:     strings.TrimSpace(*workspace),
+		ConfigFile:    *configFile,
+		DBPath:        *dbPath,
+		NoAI:          *noAI,
+		FailOnAIError: *failOnAIError,
+		Quiet:         *quiet,
+		Append:        *appendMD,
+		Open:          *openReport,
+		NoCache:       *noCache,
+		Stream:        *stream,
+		ExplicitTop:   isFlagSet(fs, "top"),
+		Report: report.ReportOptions{
+			PostTypes:     report.ParsePostTypes(*postTypes),
+			Top:           *top,
+			YearOverYear:  *yoy,
+			History:       *history,
+			RankBy:        by,
+			Network:       strings.TrimSpace(*network),
+			HashtagRankBy: hashtagBy,
+			RateBasis:     basis,
+			Decimals:      *decimals,
+			Language:      language,
+			Redact:        *redact,
+		},
+	}
+	if *dumpPrompts {
+		opts.DumpPrompts = os.Stderr
+	}
+	return report.RunStored(ctx, opts)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/christophberger/publer-analytics-report/report"
+)
+
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	latest := fs.Bool("latest", false, "pick the most recent file when a directory holds several files of one type")
+	force := fs.Bool("force", false, "accept CSV files that cover different periods")
+	strict := fs.Bool("strict", false, "fail if a numeric cell does not hold a number")
+	strictCSV := fs.Bool("strict-csv", false, "fail if a data row does not have as many fields as the header row")
+	configFile := fs.String("config", report.DefaultConfigFile, "path to the configuration file with the column mappings (default searches ./config.yaml, then the user config directory)")
+	delimiter := fs.String("delimiter", "", `CSV field separator: ",", ";", or "tab" (default detects it per file)`)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s validate [flags] <file-or-directory>\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return usageError{errors.New("missing file or directory argument")}
+	}
+
+	delim, err := report.ParseDelimiter(*delimiter)
+	if err != nil {
+		return usageError{fmt.Errorf("error parsing delimiter: %w", err)}
+	}
+
+	return report.Validate(report.Options{Input: fs.Arg(0), ConfigFile: *configFile, Latest: *latest, Force: *force, Delimiter: delim, Strict: *strict, StrictCSV: *strictCSV})
+}